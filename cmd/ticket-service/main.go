@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -12,6 +13,7 @@ import (
 	"ticket-service/internal/logger"
 	"ticket-service/internal/repository"
 	"ticket-service/internal/service"
+	"ticket-service/internal/worker"
 )
 
 func main() {
@@ -33,16 +35,52 @@ func main() {
 	assignmentRepo := repository.NewAssignmentRepository(database)
 	tripRepo := repository.NewTripRepository(database)
 	appealRepo := repository.NewAppealRepository(database)
+	contractRepo := repository.NewContractRepository(database)
+	ticketCommentRepo := repository.NewTicketCommentRepository(database)
+	shiftRepo := repository.NewDriverShiftRepository(database)
+	appealReasonRepo := repository.NewAppealReasonRepository(database)
+	appealReviewerRepo := repository.NewAppealReviewerRepository(database)
 
 	// Services
-	ticketService := service.NewTicketService(ticketRepo, tripRepo, assignmentRepo, appealRepo)
-	assignmentService := service.NewAssignmentService(assignmentRepo, ticketRepo)
-	tripService := service.NewTripService(tripRepo, ticketRepo)
-	appealService := service.NewAppealService(appealRepo, tripRepo, ticketRepo)
+	ticketService := service.NewTicketService(ticketRepo, tripRepo, assignmentRepo, appealRepo, contractRepo, ticketCommentRepo, cfg.Tickets)
+	assignmentService := service.NewAssignmentService(assignmentRepo, ticketRepo, tripRepo, shiftRepo, cfg.Shifts)
+	tripService := service.NewTripService(tripRepo, ticketRepo, assignmentRepo)
+	appealService := service.NewAppealService(appealRepo, tripRepo, ticketRepo, assignmentRepo, appealReasonRepo, appealReviewerRepo, cfg.Appeals)
+	contractService := service.NewContractService(contractRepo, cfg.Contracts)
+	ticketCommentService := service.NewTicketCommentService(ticketCommentRepo, ticketRepo, cfg.Tickets)
+	shiftService := service.NewShiftService(shiftRepo, assignmentRepo, tripRepo, cfg.Shifts)
+
+	// Фоновые задачи
+	scheduler := worker.NewScheduler(cfg.Scheduler.Interval, appLogger,
+		worker.Job{
+			Name: "escalate_unaccepted_tickets",
+			Run: func(ctx context.Context) error {
+				escalated, err := ticketService.EscalateUnaccepted(ctx)
+				if escalated > 0 {
+					appLogger.Warn().Int("count", escalated).Msg("tickets escalated: not accepted by contractor in time")
+				}
+				return err
+			},
+		},
+		worker.Job{
+			Name: "process_appeal_deadlines",
+			Run: func(ctx context.Context) error {
+				escalated, closed, err := appealService.ProcessDeadlines(ctx)
+				if escalated > 0 {
+					appLogger.Warn().Int("count", escalated).Msg("appeals escalated to akimat: review deadline expired")
+				}
+				if closed > 0 {
+					appLogger.Info().Int("count", closed).Msg("appeals closed: driver did not respond in time")
+				}
+				return err
+			},
+		},
+	)
+	scheduler.Start(context.Background())
 
 	tokenParser := auth.NewParser(cfg.Auth.AccessSecret)
 
-	handler := httphandler.NewHandler(ticketService, assignmentService, tripService, appealService, appLogger)
+	handler := httphandler.NewHandler(ticketService, assignmentService, tripService, appealService, contractService, ticketCommentService, shiftService, appLogger)
 	authMiddleware := middleware.Auth(tokenParser)
 	router := httphandler.NewRouter(handler, authMiddleware, cfg.Environment)
 
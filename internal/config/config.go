@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -30,12 +33,64 @@ type ExternalServicesConfig struct {
 	AIServiceURL         string
 }
 
+// TicketConfig задает целевые сроки (SLA) выполнения тикета в зависимости от приоритета
+type TicketConfig struct {
+	EmergencySLA time.Duration
+	HighSLA      time.Duration
+	NormalSLA    time.Duration
+	// CommentEditWindow - сколько времени автор может править или удалять свой комментарий
+	CommentEditWindow time.Duration
+	// AcceptanceTimeout - сколько подрядчик может не подтверждать тикет до эскалации
+	AcceptanceTimeout time.Duration
+}
+
+// AppealConfig задает сроки по обжалованиям: окно подачи после рейса, срок рассмотрения КГУ
+// (после него обжалование передается Акимату) и срок ответа водителя на запрос информации
+// (после него обжалование закрывается)
+type AppealConfig struct {
+	FilingWindow    time.Duration
+	ReviewTimeout   time.Duration
+	ResponseTimeout time.Duration
+	// MaxAttachmentSize - предельный размер одного вложения в байтах,
+	// MaxAttachments - предельное число вложений на обжалование вместе с комментариями
+	MaxAttachmentSize int64
+	MaxAttachments    int
+	// CommentEditWindow - окно, в которое автор сообщения в переписке по обжалованию
+	// (ответ сторонам или внутренняя заметка КГУ/Акимата) может его править или удалить.
+	// Прежний текст каждой правки остается в аудите
+	CommentEditWindow time.Duration
+}
+
+// SchedulerConfig - фоновые задачи сервиса
+type SchedulerConfig struct {
+	Interval time.Duration
+}
+
+// ShiftConfig - ограничения рабочего времени водителей.
+// При BlockOnLimit превышение лимита запрещает новые назначения, иначе только предупреждает
+type ShiftConfig struct {
+	DailyLimit   time.Duration
+	WeeklyLimit  time.Duration
+	BlockOnLimit bool
+}
+
+// ContractConfig задает пороги (в процентах от лимита объема), при которых
+// по договору выдается предупреждение
+type ContractConfig struct {
+	AlertThresholds []float64
+}
+
 type Config struct {
 	Environment      string
 	HTTP             HTTPConfig
 	DB               DBConfig
 	Auth             AuthConfig
 	ExternalServices ExternalServicesConfig
+	Tickets          TicketConfig
+	Contracts        ContractConfig
+	Appeals          AppealConfig
+	Scheduler        SchedulerConfig
+	Shifts           ShiftConfig
 }
 
 func Load() (*Config, error) {
@@ -72,6 +127,31 @@ func Load() (*Config, error) {
 			OperationsServiceURL: v.GetString("OPERATIONS_SERVICE_URL"),
 			AIServiceURL:         v.GetString("AI_SERVICE_URL"),
 		},
+		Tickets: TicketConfig{
+			EmergencySLA: v.GetDuration("TICKET_SLA_EMERGENCY"),
+			HighSLA:      v.GetDuration("TICKET_SLA_HIGH"),
+			NormalSLA:    v.GetDuration("TICKET_SLA_NORMAL"),
+
+			CommentEditWindow: v.GetDuration("TICKET_COMMENT_EDIT_WINDOW"),
+			AcceptanceTimeout: v.GetDuration("TICKET_ACCEPTANCE_TIMEOUT"),
+		},
+		Appeals: AppealConfig{
+			FilingWindow:    v.GetDuration("APPEAL_FILING_WINDOW"),
+			ReviewTimeout:   v.GetDuration("APPEAL_REVIEW_TIMEOUT"),
+			ResponseTimeout: v.GetDuration("APPEAL_RESPONSE_TIMEOUT"),
+
+			MaxAttachmentSize: v.GetInt64("APPEAL_ATTACHMENT_MAX_SIZE_MB") << 20,
+			MaxAttachments:    v.GetInt("APPEAL_ATTACHMENTS_MAX"),
+			CommentEditWindow: v.GetDuration("APPEAL_COMMENT_EDIT_WINDOW"),
+		},
+		Scheduler: SchedulerConfig{
+			Interval: v.GetDuration("SCHEDULER_INTERVAL"),
+		},
+		Shifts: ShiftConfig{
+			DailyLimit:   v.GetDuration("DRIVER_DAILY_HOURS_LIMIT"),
+			WeeklyLimit:  v.GetDuration("DRIVER_WEEKLY_HOURS_LIMIT"),
+			BlockOnLimit: strings.EqualFold(v.GetString("DRIVER_HOURS_LIMIT_MODE"), "block"),
+		},
 	}
 
 	if cfg.HTTP.Host == "" {
@@ -83,6 +163,57 @@ func Load() (*Config, error) {
 	if cfg.Environment == "" {
 		cfg.Environment = "development"
 	}
+	if cfg.Tickets.EmergencySLA == 0 {
+		cfg.Tickets.EmergencySLA = 6 * time.Hour
+	}
+	if cfg.Tickets.HighSLA == 0 {
+		cfg.Tickets.HighSLA = 24 * time.Hour
+	}
+	if cfg.Tickets.NormalSLA == 0 {
+		cfg.Tickets.NormalSLA = 72 * time.Hour
+	}
+	if cfg.Tickets.CommentEditWindow == 0 {
+		cfg.Tickets.CommentEditWindow = 15 * time.Minute
+	}
+	if cfg.Tickets.AcceptanceTimeout == 0 {
+		cfg.Tickets.AcceptanceTimeout = 2 * time.Hour
+	}
+	if cfg.Appeals.FilingWindow == 0 {
+		cfg.Appeals.FilingWindow = 72 * time.Hour
+	}
+	if cfg.Appeals.ReviewTimeout == 0 {
+		cfg.Appeals.ReviewTimeout = 120 * time.Hour
+	}
+	if cfg.Appeals.ResponseTimeout == 0 {
+		cfg.Appeals.ResponseTimeout = 48 * time.Hour
+	}
+	if cfg.Appeals.MaxAttachmentSize == 0 {
+		cfg.Appeals.MaxAttachmentSize = 50 << 20
+	}
+	if cfg.Appeals.MaxAttachments == 0 {
+		cfg.Appeals.MaxAttachments = 10
+	}
+	if cfg.Appeals.CommentEditWindow == 0 {
+		cfg.Appeals.CommentEditWindow = 15 * time.Minute
+	}
+	if cfg.Scheduler.Interval == 0 {
+		cfg.Scheduler.Interval = time.Minute
+	}
+	if cfg.Shifts.DailyLimit == 0 {
+		cfg.Shifts.DailyLimit = 12 * time.Hour
+	}
+	if cfg.Shifts.WeeklyLimit == 0 {
+		cfg.Shifts.WeeklyLimit = 60 * time.Hour
+	}
+
+	thresholds, err := parseThresholds(v.GetString("CONTRACT_ALERT_THRESHOLDS"))
+	if err != nil {
+		return nil, err
+	}
+	if len(thresholds) == 0 {
+		thresholds = []float64{80, 100}
+	}
+	cfg.Contracts.AlertThresholds = thresholds
 
 	if err := validate(cfg); err != nil {
 		return nil, err
@@ -91,6 +222,24 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// parseThresholds разбирает список порогов вида "80,100"
+func parseThresholds(raw string) ([]float64, error) {
+	var thresholds []float64
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid CONTRACT_ALERT_THRESHOLDS value %q", part)
+		}
+		thresholds = append(thresholds, value)
+	}
+	sort.Float64s(thresholds)
+	return thresholds, nil
+}
+
 func validate(cfg *Config) error {
 	if cfg.DB.DSN == "" {
 		return fmt.Errorf("DB_DSN is required")
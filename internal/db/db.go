@@ -27,6 +27,8 @@ func New(cfg *config.Config, log zerolog.Logger) (*gorm.DB, error) {
 
 	database, err := gorm.Open(postgres.Open(dbCfg.DSN), &gorm.Config{
 		Logger: gormLog,
+		// Нарушение уникальности возвращается как gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, err
@@ -116,6 +116,91 @@ var migrationStatements = []string{
 	$$;`,
 	`CREATE INDEX IF NOT EXISTS idx_tickets_created_by_org_id ON tickets (created_by_org_id);`,
 	`CREATE INDEX IF NOT EXISTS idx_tickets_status ON tickets (status);`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'ticket_priority') THEN
+			CREATE TYPE ticket_priority AS ENUM ('EMERGENCY', 'HIGH', 'NORMAL');
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		-- Приоритет тикета и целевой срок SLA
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'priority') THEN
+			ALTER TABLE tickets ADD COLUMN priority ticket_priority NOT NULL DEFAULT 'NORMAL';
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'sla_deadline_at') THEN
+			ALTER TABLE tickets ADD COLUMN sla_deadline_at TIMESTAMPTZ;
+		END IF;
+	END
+	$$;`,
+	`CREATE INDEX IF NOT EXISTS idx_tickets_priority ON tickets (priority);`,
+	`DO $$
+	BEGIN
+		-- Обоснование параллельных работ на одном участке
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'overlap_reason') THEN
+			ALTER TABLE tickets ADD COLUMN overlap_reason TEXT;
+		END IF;
+	END
+	$$;`,
+	`CREATE INDEX IF NOT EXISTS idx_tickets_area_planned_window ON tickets (cleaning_area_id, planned_start_at, planned_end_at);`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'ticket_acceptance') THEN
+			CREATE TYPE ticket_acceptance AS ENUM ('PENDING', 'ACCEPTED', 'DECLINED');
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		-- Подтверждение тикета подрядчиком. Существующие тикеты считаем принятыми,
+		-- новые создаются в статусе PENDING
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'acceptance') THEN
+			ALTER TABLE tickets ADD COLUMN acceptance ticket_acceptance NOT NULL DEFAULT 'ACCEPTED';
+			ALTER TABLE tickets ALTER COLUMN acceptance SET DEFAULT 'PENDING';
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'accept_due_at') THEN
+			ALTER TABLE tickets ADD COLUMN accept_due_at TIMESTAMPTZ;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'accepted_at') THEN
+			ALTER TABLE tickets ADD COLUMN accepted_at TIMESTAMPTZ;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'declined_at') THEN
+			ALTER TABLE tickets ADD COLUMN declined_at TIMESTAMPTZ;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'decline_reason') THEN
+			ALTER TABLE tickets ADD COLUMN decline_reason TEXT;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'escalated_at') THEN
+			ALTER TABLE tickets ADD COLUMN escalated_at TIMESTAMPTZ;
+		END IF;
+	END
+	$$;`,
+	`CREATE INDEX IF NOT EXISTS idx_tickets_acceptance ON tickets (acceptance, accept_due_at);`,
+	`CREATE TABLE IF NOT EXISTS contracts (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		number VARCHAR(100) NOT NULL,
+		contractor_id UUID NOT NULL,
+		created_by_org_id UUID NOT NULL,
+		starts_at TIMESTAMPTZ NOT NULL,
+		ends_at TIMESTAMPTZ NOT NULL,
+		max_volume_m3 DOUBLE PRECISION NOT NULL CHECK (max_volume_m3 > 0),
+		unit_tariff DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (unit_tariff >= 0),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CHECK (ends_at > starts_at)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_contracts_contractor_id ON contracts (contractor_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_contracts_created_by_org_id ON contracts (created_by_org_id);`,
 	`CREATE TABLE IF NOT EXISTS ticket_assignments (
 		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 		ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
@@ -140,6 +225,54 @@ var migrationStatements = []string{
 	`CREATE INDEX IF NOT EXISTS idx_ticket_assignments_ticket_id ON ticket_assignments (ticket_id);`,
 	`CREATE INDEX IF NOT EXISTS idx_ticket_assignments_driver_id ON ticket_assignments (driver_id);`,
 	`CREATE INDEX IF NOT EXISTS idx_ticket_assignments_vehicle_id ON ticket_assignments (vehicle_id);`,
+	`DO $$
+	BEGIN
+		-- Обоснование назначения водителя/техники поверх пересекающихся работ
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'ticket_assignments' AND column_name = 'overlap_reason') THEN
+			ALTER TABLE ticket_assignments ADD COLUMN overlap_reason TEXT;
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		-- Кто и почему снял назначение
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'ticket_assignments' AND column_name = 'unassigned_by') THEN
+			ALTER TABLE ticket_assignments ADD COLUMN unassigned_by UUID;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'ticket_assignments' AND column_name = 'unassign_reason') THEN
+			ALTER TABLE ticket_assignments ADD COLUMN unassign_reason TEXT;
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		-- Время и координаты отметок водителя
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'ticket_assignments' AND column_name = 'started_at') THEN
+			ALTER TABLE ticket_assignments ADD COLUMN started_at TIMESTAMPTZ;
+			ALTER TABLE ticket_assignments ADD COLUMN completed_at TIMESTAMPTZ;
+			ALTER TABLE ticket_assignments ADD COLUMN started_lat DOUBLE PRECISION;
+			ALTER TABLE ticket_assignments ADD COLUMN started_lon DOUBLE PRECISION;
+			ALTER TABLE ticket_assignments ADD COLUMN completed_lat DOUBLE PRECISION;
+			ALTER TABLE ticket_assignments ADD COLUMN completed_lon DOUBLE PRECISION;
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		-- Ссылка на назначение, замененное при смене техники/водителя
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'ticket_assignments' AND column_name = 'replaces_id') THEN
+			ALTER TABLE ticket_assignments ADD COLUMN replaces_id UUID REFERENCES ticket_assignments(id);
+		END IF;
+	END
+	$$;`,
+	`CREATE INDEX IF NOT EXISTS idx_ticket_assignments_replaces_id ON ticket_assignments (replaces_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_ticket_assignments_active_driver ON ticket_assignments (driver_id) WHERE is_active;`,
+	`CREATE INDEX IF NOT EXISTS idx_ticket_assignments_active_vehicle ON ticket_assignments (vehicle_id) WHERE is_active;`,
 	`CREATE TABLE IF NOT EXISTS trips (
 		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 		ticket_id UUID REFERENCES tickets(id) ON DELETE SET NULL,
@@ -288,6 +421,79 @@ var migrationStatements = []string{
 	`CREATE INDEX IF NOT EXISTS idx_appeals_trip_id ON appeals (trip_id);`,
 	`CREATE INDEX IF NOT EXISTS idx_appeals_ticket_id ON appeals (ticket_id);`,
 	`CREATE INDEX IF NOT EXISTS idx_appeals_status ON appeals (status);`,
+	`DO $$ 
+	BEGIN
+		-- Сроки рассмотрения обжалований
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'review_due_at') THEN
+			ALTER TABLE appeals ADD COLUMN review_due_at TIMESTAMPTZ;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'response_due_at') THEN
+			ALTER TABLE appeals ADD COLUMN response_due_at TIMESTAMPTZ;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'escalated_at') THEN
+			ALTER TABLE appeals ADD COLUMN escalated_at TIMESTAMPTZ;
+		END IF;
+	END
+	$$;`,
+	`DO $$ 
+	BEGIN
+		-- Решение по обжалованию и ссылка на обжалование, решение по которому обжалуется в Акимате
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'decision') THEN
+			ALTER TABLE appeals ADD COLUMN decision appeal_status;
+			UPDATE appeals SET decision = status WHERE status IN ('APPROVED', 'REJECTED');
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'parent_appeal_id') THEN
+			ALTER TABLE appeals ADD COLUMN parent_appeal_id UUID REFERENCES appeals(id) ON DELETE SET NULL;
+		END IF;
+	END
+	$$;`,
+	// Перед уникальным индексом закрываем лишние открытые обжалования по рейсу, оставляя самое раннее
+	`UPDATE appeals SET status = 'CLOSED', resolved_at = COALESCE(resolved_at, NOW())
+		WHERE status NOT IN ('APPROVED', 'REJECTED', 'CLOSED')
+		AND EXISTS (SELECT 1 FROM appeals earlier
+			WHERE earlier.trip_id = appeals.trip_id
+			AND earlier.status NOT IN ('APPROVED', 'REJECTED', 'CLOSED')
+			AND (earlier.created_at, earlier.id) < (appeals.created_at, appeals.id));`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS uq_appeals_open_trip ON appeals (trip_id)
+		WHERE status NOT IN ('APPROVED', 'REJECTED', 'CLOSED');`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS uq_appeals_parent_appeal_id ON appeals (parent_appeal_id);`,
+	`DO $$ 
+	BEGIN
+		-- Назначенный рассматривающий инспектор КГУ
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'reviewer_id') THEN
+			ALTER TABLE appeals ADD COLUMN reviewer_id UUID;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'reviewer_assigned_at') THEN
+			ALTER TABLE appeals ADD COLUMN reviewer_assigned_at TIMESTAMPTZ;
+		END IF;
+	END
+	$$;`,
+	`CREATE INDEX IF NOT EXISTS idx_appeals_reviewer_id ON appeals (reviewer_id, status);`,
+	`DO $$ 
+	BEGIN
+		-- Подавший обжалование и водитель, чей рейс обжалуется
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'created_by_role') THEN
+			ALTER TABLE appeals ADD COLUMN created_by_role VARCHAR(32) NOT NULL DEFAULT 'DRIVER';
+			ALTER TABLE appeals ALTER COLUMN created_by_role DROP DEFAULT;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'driver_id') THEN
+			ALTER TABLE appeals ADD COLUMN driver_id UUID;
+			UPDATE appeals SET driver_id = trips.driver_id FROM trips WHERE trips.id = appeals.trip_id;
+		END IF;
+	END
+	$$;`,
+	`CREATE INDEX IF NOT EXISTS idx_appeals_driver_id ON appeals (driver_id, created_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_appeals_review_due_at ON appeals (status, review_due_at) WHERE escalated_at IS NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_appeals_response_due_at ON appeals (status, response_due_at);`,
 	`CREATE TABLE IF NOT EXISTS appeal_comments (
 		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 		appeal_id UUID NOT NULL REFERENCES appeals(id) ON DELETE CASCADE,
@@ -296,6 +502,171 @@ var migrationStatements = []string{
 		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 	);`,
 	`CREATE INDEX IF NOT EXISTS idx_appeal_comments_appeal_id ON appeal_comments (appeal_id);`,
+	`DO $$
+	BEGIN
+		-- Внутренние заметки КГУ/Акимата и правка своих комментариев
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'appeal_comments' AND column_name = 'visibility') THEN
+			ALTER TABLE appeal_comments ADD COLUMN visibility VARCHAR(16) NOT NULL DEFAULT 'PUBLIC';
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'appeal_comments' AND column_name = 'edited_at') THEN
+			ALTER TABLE appeal_comments ADD COLUMN edited_at TIMESTAMPTZ;
+			ALTER TABLE appeal_comments ADD COLUMN deleted_at TIMESTAMPTZ;
+			ALTER TABLE appeal_comments ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+		END IF;
+	END
+	$$;`,
+	`CREATE TABLE IF NOT EXISTS appeal_comment_edits (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		comment_id UUID NOT NULL REFERENCES appeal_comments(id) ON DELETE CASCADE,
+		action VARCHAR(16) NOT NULL,
+		previous_content TEXT NOT NULL,
+		edited_by_user_id UUID NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_appeal_comment_edits_comment_id ON appeal_comment_edits (comment_id, created_at);`,
+	`CREATE TABLE IF NOT EXISTS appeal_reads (
+		appeal_id UUID NOT NULL REFERENCES appeals(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL,
+		last_read_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (appeal_id, user_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_appeals_created_by_user_id ON appeals (created_by_user_id, created_at);`,
+	`CREATE TABLE IF NOT EXISTS appeal_reviewers (
+		org_id UUID NOT NULL,
+		user_id UUID NOT NULL,
+		is_active BOOLEAN NOT NULL DEFAULT TRUE,
+		last_assigned_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (org_id, user_id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS appeal_reviewer_assignments (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		appeal_id UUID NOT NULL REFERENCES appeals(id) ON DELETE CASCADE,
+		reviewer_id UUID NOT NULL,
+		previous_reviewer_id UUID,
+		assigned_by_user_id UUID,
+		reason TEXT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_appeal_reviewer_assignments_appeal_id ON appeal_reviewer_assignments (appeal_id, created_at);`,
+	`CREATE TABLE IF NOT EXISTS appeal_reason_types (
+		code VARCHAR(50) PRIMARY KEY,
+		title_ru VARCHAR(255) NOT NULL,
+		title_kk VARCHAR(255) NOT NULL,
+		evidence_required BOOLEAN NOT NULL DEFAULT FALSE,
+		is_active BOOLEAN NOT NULL DEFAULT TRUE,
+		version BIGINT NOT NULL DEFAULT 1,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE TABLE IF NOT EXISTS appeal_reason_trip_statuses (
+		reason_code VARCHAR(50) NOT NULL REFERENCES appeal_reason_types(code) ON DELETE CASCADE,
+		trip_status trip_status NOT NULL,
+		PRIMARY KEY (reason_code, trip_status)
+	);`,
+	// Базовый справочник причин; дальше его ведет Акимат
+	`INSERT INTO appeal_reason_types (code, title_ru, title_kk, evidence_required) VALUES
+		('PLATE_MISREAD', 'Номер распознан неверно', 'Нөмір қате танылды', TRUE),
+		('VOLUME_MISMEASURED', 'Объем определен неверно', 'Көлем қате анықталды', TRUE),
+		('ROUTE_DEVIATION_JUSTIFIED', 'Отклонение от маршрута обосновано', 'Бағыттан ауытқу негізделген', FALSE),
+		('ASSIGNMENT_NOT_REGISTERED', 'Назначение не учтено', 'Тағайындау ескерілмеді', FALSE),
+		('OTHER', 'Другое', 'Басқа', FALSE)
+	ON CONFLICT (code) DO NOTHING;`,
+	`INSERT INTO appeal_reason_trip_statuses (reason_code, trip_status) VALUES
+		('PLATE_MISREAD', 'MISMATCH_PLATE'),
+		('VOLUME_MISMEASURED', 'SUSPICIOUS_VOLUME'),
+		('ROUTE_DEVIATION_JUSTIFIED', 'ROUTE_VIOLATION'),
+		('ASSIGNMENT_NOT_REGISTERED', 'NO_ASSIGNMENT'),
+		('OTHER', 'ROUTE_VIOLATION'),
+		('OTHER', 'MISMATCH_PLATE'),
+		('OTHER', 'NO_ASSIGNMENT'),
+		('OTHER', 'SUSPICIOUS_VOLUME')
+	ON CONFLICT DO NOTHING;`,
+	`CREATE TABLE IF NOT EXISTS appeal_attachments (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		appeal_id UUID NOT NULL REFERENCES appeals(id) ON DELETE CASCADE,
+		comment_id UUID REFERENCES appeal_comments(id) ON DELETE CASCADE,
+		kind VARCHAR(16) NOT NULL,
+		url TEXT NOT NULL,
+		file_name VARCHAR(255) NOT NULL,
+		content_type VARCHAR(100) NOT NULL,
+		size_bytes BIGINT NOT NULL,
+		uploaded_by_user_id UUID NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_appeal_attachments_appeal_id ON appeal_attachments (appeal_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_appeal_attachments_comment_id ON appeal_attachments (comment_id);`,
+	`CREATE TABLE IF NOT EXISTS trip_status_history (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+		from_status trip_status NOT NULL,
+		to_status trip_status NOT NULL,
+		from_assignment_id UUID,
+		to_assignment_id UUID,
+		appeal_id UUID REFERENCES appeals(id) ON DELETE SET NULL,
+		changed_by_user_id UUID NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_trip_status_history_trip_id ON trip_status_history (trip_id, created_at);`,
+	`DO $$
+	BEGIN
+		-- Колонка version для оптимистической блокировки
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'tickets' AND column_name = 'version') THEN
+			ALTER TABLE tickets ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'ticket_assignments' AND column_name = 'version') THEN
+			ALTER TABLE ticket_assignments ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
+			WHERE table_name = 'appeals' AND column_name = 'version') THEN
+			ALTER TABLE appeals ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+		END IF;
+	END
+	$$;`,
+	`CREATE TABLE IF NOT EXISTS ticket_comments (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+		created_by_user_id UUID NOT NULL,
+		author_role VARCHAR(32) NOT NULL,
+		content TEXT NOT NULL,
+		edited_at TIMESTAMPTZ,
+		deleted_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_ticket_comments_ticket_id ON ticket_comments (ticket_id, created_at);`,
+	`CREATE TABLE IF NOT EXISTS ticket_comment_mentions (
+		comment_id UUID NOT NULL REFERENCES ticket_comments(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL,
+		PRIMARY KEY (comment_id, user_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_ticket_comment_mentions_user_id ON ticket_comment_mentions (user_id);`,
+	`CREATE TABLE IF NOT EXISTS ticket_comment_reads (
+		ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL,
+		last_read_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (ticket_id, user_id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS driver_shifts (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		driver_id UUID NOT NULL,
+		contractor_id UUID NOT NULL,
+		started_at TIMESTAMPTZ NOT NULL,
+		ended_at TIMESTAMPTZ,
+		opened_by UUID NOT NULL,
+		closed_by UUID,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CHECK (ended_at IS NULL OR ended_at >= started_at)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_driver_shifts_driver_id ON driver_shifts (driver_id, started_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_driver_shifts_contractor_id ON driver_shifts (contractor_id, started_at);`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS uq_driver_shifts_open ON driver_shifts (driver_id) WHERE ended_at IS NULL;`,
 	`CREATE OR REPLACE FUNCTION set_updated_at()
 	RETURNS TRIGGER AS $$
 	BEGIN
@@ -343,6 +714,66 @@ var migrationStatements = []string{
 		END IF;
 	END
 	$$;`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'trg_contracts_updated_at') THEN
+			CREATE TRIGGER trg_contracts_updated_at
+				BEFORE UPDATE ON contracts
+				FOR EACH ROW
+				EXECUTE PROCEDURE set_updated_at();
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'trg_ticket_comments_updated_at') THEN
+			CREATE TRIGGER trg_ticket_comments_updated_at
+				BEFORE UPDATE ON ticket_comments
+				FOR EACH ROW
+				EXECUTE PROCEDURE set_updated_at();
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'trg_driver_shifts_updated_at') THEN
+			CREATE TRIGGER trg_driver_shifts_updated_at
+				BEFORE UPDATE ON driver_shifts
+				FOR EACH ROW
+				EXECUTE PROCEDURE set_updated_at();
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'trg_appeal_reviewers_updated_at') THEN
+			CREATE TRIGGER trg_appeal_reviewers_updated_at
+				BEFORE UPDATE ON appeal_reviewers
+				FOR EACH ROW
+				EXECUTE PROCEDURE set_updated_at();
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'trg_appeal_reason_types_updated_at') THEN
+			CREATE TRIGGER trg_appeal_reason_types_updated_at
+				BEFORE UPDATE ON appeal_reason_types
+				FOR EACH ROW
+				EXECUTE PROCEDURE set_updated_at();
+		END IF;
+	END
+	$$;`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'trg_appeal_comments_updated_at') THEN
+			CREATE TRIGGER trg_appeal_comments_updated_at
+				BEFORE UPDATE ON appeal_comments
+				FOR EACH ROW
+				EXECUTE PROCEDURE set_updated_at();
+		END IF;
+	END
+	$$;`,
 }
 
 func runMigrations(db *gorm.DB) error {
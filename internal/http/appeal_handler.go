@@ -0,0 +1,491 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"ticket-service/internal/http/middleware"
+	"ticket-service/internal/model"
+	"ticket-service/internal/repository"
+	"ticket-service/internal/service"
+)
+
+// registerAppealReview регистрирует маршруты рассмотрения обжалований (КГУ и Акимат)
+func (h *Handler) registerAppealReview(group *gin.RouterGroup) {
+	group.GET("/appeals", h.listAppeals)
+	group.GET("/appeals/:id", h.getAppealDetails)
+	group.PUT("/appeals/:id/review", h.reviewAppeal)
+	group.POST("/appeals/:id/comments", h.addAppealComment)
+	group.GET("/appeals/:id/comments", h.getAppealComments)
+	group.PUT("/appeals/:id/comments/read", h.markAppealRead)
+	group.PUT("/appeals/:id/comments/:comment_id", h.updateAppealComment)
+	group.DELETE("/appeals/:id/comments/:comment_id", h.deleteAppealComment)
+	group.POST("/appeals/:id/attachments", h.addAppealAttachments)
+	group.GET("/appeal-reasons", h.listAppealReasons)
+}
+
+func (h *Handler) listAppeals(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	filter := repository.AppealListFilter{}
+
+	status := strings.TrimSpace(c.Query("status"))
+	if status != "" {
+		as := model.AppealStatus(strings.ToUpper(status))
+		if !as.IsValid() {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid status"))
+			return
+		}
+		filter.Status = &as
+	}
+
+	ticketID := strings.TrimSpace(c.Query("ticket_id"))
+	if ticketID != "" {
+		if _, err := uuid.Parse(ticketID); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid ticket_id"))
+			return
+		}
+		filter.TicketID = &ticketID
+	}
+
+	contractorID := strings.TrimSpace(c.Query("contractor_id"))
+	if contractorID != "" {
+		if _, err := uuid.Parse(contractorID); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid contractor_id"))
+			return
+		}
+		filter.ContractorID = &contractorID
+	}
+
+	reasonType := strings.TrimSpace(c.Query("reason_type"))
+	if reasonType != "" {
+		filter.ReasonType = &reasonType
+	}
+
+	createdFrom := strings.TrimSpace(c.Query("created_from"))
+	if createdFrom != "" {
+		if _, err := time.Parse(time.RFC3339, createdFrom); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid created_from"))
+			return
+		}
+		filter.CreatedFrom = &createdFrom
+	}
+
+	createdTo := strings.TrimSpace(c.Query("created_to"))
+	if createdTo != "" {
+		if _, err := time.Parse(time.RFC3339, createdTo); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid created_to"))
+			return
+		}
+		filter.CreatedTo = &createdTo
+	}
+
+	reviewerID := strings.TrimSpace(c.Query("reviewer_id"))
+	if reviewerID != "" {
+		if _, err := uuid.Parse(reviewerID); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid reviewer_id"))
+			return
+		}
+		filter.ReviewerID = &reviewerID
+	}
+
+	if escalated := strings.TrimSpace(c.Query("escalated")); escalated != "" {
+		value, err := strconv.ParseBool(escalated)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid escalated"))
+			return
+		}
+		filter.Escalated = &value
+	}
+
+	appeals, err := h.appealService.List(c.Request.Context(), principal, filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(appeals))
+}
+
+func (h *Handler) getAppealDetails(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid appeal id"))
+		return
+	}
+
+	details, err := h.appealService.GetDetails(c.Request.Context(), principal, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, details.Appeal.Version)
+	c.JSON(http.StatusOK, successResponse(details))
+}
+
+func (h *Handler) reviewAppeal(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid appeal id"))
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	var req struct {
+		Status             string  `json:"status" binding:"required"`
+		AdminResponse      *string `json:"admin_response"`
+		TripStatus         *string `json:"trip_status"`
+		TicketAssignmentID *string `json:"ticket_assignment_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	input := service.ReviewAppealInput{
+		Status:             model.AppealStatus(strings.ToUpper(strings.TrimSpace(req.Status))),
+		AdminResponse:      req.AdminResponse,
+		TripStatus:         req.TripStatus,
+		TicketAssignmentID: req.TicketAssignmentID,
+	}
+	result, err := h.appealService.UpdateStatus(c.Request.Context(), principal, id, input, expectedVersion)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, result.Appeal.Version)
+	c.JSON(http.StatusOK, successResponse(result))
+}
+
+// attachmentRequest - файл, уже загруженный клиентом во внешнее хранилище
+type attachmentRequest struct {
+	URL         string `json:"url" binding:"required"`
+	FileName    string `json:"file_name" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	SizeBytes   int64  `json:"size_bytes" binding:"required"`
+}
+
+func toAttachmentInputs(reqs []attachmentRequest) []service.AttachmentInput {
+	inputs := make([]service.AttachmentInput, 0, len(reqs))
+	for _, r := range reqs {
+		inputs = append(inputs, service.AttachmentInput{
+			URL:         r.URL,
+			FileName:    r.FileName,
+			ContentType: r.ContentType,
+			SizeBytes:   r.SizeBytes,
+		})
+	}
+	return inputs
+}
+
+func (h *Handler) addAppealAttachments(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid appeal id"))
+		return
+	}
+
+	var req struct {
+		Attachments []attachmentRequest `json:"attachments" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	attachments, err := h.appealService.AddAttachments(c.Request.Context(), principal, id, toAttachmentInputs(req.Attachments))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, successResponse(attachments))
+}
+
+// appealReasonRequest - причина обжалования в справочнике
+type appealReasonRequest struct {
+	TitleRu          string   `json:"title_ru" binding:"required"`
+	TitleKk          string   `json:"title_kk" binding:"required"`
+	TripStatuses     []string `json:"trip_statuses" binding:"required,min=1"`
+	EvidenceRequired bool     `json:"evidence_required"`
+	IsActive         *bool    `json:"is_active"`
+}
+
+func (r appealReasonRequest) toInput(code string) service.AppealReasonInput {
+	return service.AppealReasonInput{
+		Code:             code,
+		TitleRu:          r.TitleRu,
+		TitleKk:          r.TitleKk,
+		TripStatuses:     r.TripStatuses,
+		EvidenceRequired: r.EvidenceRequired,
+		IsActive:         r.IsActive,
+	}
+}
+
+func (h *Handler) listAppealReasons(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	includeInactive := strings.EqualFold(strings.TrimSpace(c.Query("include_inactive")), "true")
+	tripStatus := strings.TrimSpace(c.Query("trip_status"))
+
+	reasons, err := h.appealService.ListReasons(c.Request.Context(), principal, includeInactive, tripStatus)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(reasons))
+}
+
+func (h *Handler) createAppealReason(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+		appealReasonRequest
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	reason, err := h.appealService.CreateReason(c.Request.Context(), principal, req.toInput(req.Code))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, reason.Version)
+	c.JSON(http.StatusCreated, successResponse(reason))
+}
+
+func (h *Handler) updateAppealReason(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	code := strings.TrimSpace(c.Param("code"))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid reason code"))
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	var req appealReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	reason, err := h.appealService.UpdateReason(c.Request.Context(), principal, code, req.toInput(code), expectedVersion)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, reason.Version)
+	c.JSON(http.StatusOK, successResponse(reason))
+}
+
+func (h *Handler) escalateAppeal(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid appeal id"))
+		return
+	}
+
+	var req struct {
+		Comment     string              `json:"comment" binding:"required"`
+		Attachments []attachmentRequest `json:"attachments" binding:"omitempty,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	appeal, err := h.appealService.Escalate(c.Request.Context(), principal, id, req.Comment, toAttachmentInputs(req.Attachments))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, successResponse(appeal))
+}
+
+func (h *Handler) listAppealReviewers(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	reviewers, err := h.appealService.ListReviewers(c.Request.Context(), principal)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(reviewers))
+}
+
+func (h *Handler) saveAppealReviewer(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	userID := strings.TrimSpace(c.Param("user_id"))
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid user id"))
+		return
+	}
+
+	var req struct {
+		IsActive *bool `json:"is_active" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	reviewer, err := h.appealService.SaveReviewer(c.Request.Context(), principal, userID, *req.IsActive)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(reviewer))
+}
+
+func (h *Handler) assignAppealReviewer(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid appeal id"))
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	// Без reviewer_id инспектор выбирается по очереди
+	var req struct {
+		ReviewerID string `json:"reviewer_id"`
+		Reason     string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	appeal, err := h.appealService.AssignReviewer(c.Request.Context(), principal, id, req.ReviewerID, req.Reason, expectedVersion)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, appeal.Version)
+	c.JSON(http.StatusOK, successResponse(appeal))
+}
+
+func (h *Handler) getMyAppealQueue(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	appeals, err := h.appealService.MyQueue(c.Request.Context(), principal)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(appeals))
+}
+
+func (h *Handler) getAppealReviewerWorkload(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	workload, err := h.appealService.ReviewerWorkload(c.Request.Context(), principal)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(workload))
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"ticket-service/internal/http/middleware"
+	"ticket-service/internal/repository"
+	"ticket-service/internal/service"
+)
+
+func (h *Handler) createContract(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	var req struct {
+		Number       string  `json:"number" binding:"required"`
+		ContractorID string  `json:"contractor_id" binding:"required"`
+		StartsAt     string  `json:"starts_at" binding:"required"`
+		EndsAt       string  `json:"ends_at" binding:"required"`
+		MaxVolumeM3  float64 `json:"max_volume_m3" binding:"required"`
+		UnitTariff   float64 `json:"unit_tariff"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	contract, err := h.contractService.Create(c.Request.Context(), principal, service.CreateContractInput{
+		Number:       req.Number,
+		ContractorID: req.ContractorID,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		MaxVolumeM3:  req.MaxVolumeM3,
+		UnitTariff:   req.UnitTariff,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, successResponse(contract))
+}
+
+func (h *Handler) listContracts(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	filter := repository.ContractListFilter{}
+
+	contractorID := strings.TrimSpace(c.Query("contractor_id"))
+	if contractorID != "" {
+		if _, err := uuid.Parse(contractorID); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid contractor_id"))
+			return
+		}
+		filter.ContractorID = &contractorID
+	}
+
+	activeAt := strings.TrimSpace(c.Query("active_at"))
+	if activeAt != "" {
+		if _, err := time.Parse(time.RFC3339, activeAt); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid active_at"))
+			return
+		}
+		filter.ActiveAt = &activeAt
+	}
+
+	alertsOnly := strings.EqualFold(strings.TrimSpace(c.Query("alerts_only")), "true")
+
+	contracts, err := h.contractService.List(c.Request.Context(), principal, filter, alertsOnly)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(contracts))
+}
+
+func (h *Handler) getContract(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid contract id"))
+		return
+	}
+
+	usage, err := h.contractService.Get(c.Request.Context(), principal, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(usage))
+}
@@ -2,7 +2,10 @@ package http
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +22,9 @@ type Handler struct {
 	assignmentService *service.AssignmentService
 	tripService       *service.TripService
 	appealService     *service.AppealService
+	contractService   *service.ContractService
+	commentService    *service.TicketCommentService
+	shiftService      *service.ShiftService
 	log               zerolog.Logger
 }
 
@@ -27,6 +33,9 @@ func NewHandler(
 	assignmentService *service.AssignmentService,
 	tripService *service.TripService,
 	appealService *service.AppealService,
+	contractService *service.ContractService,
+	commentService *service.TicketCommentService,
+	shiftService *service.ShiftService,
 	log zerolog.Logger,
 ) *Handler {
 	return &Handler{
@@ -34,6 +43,9 @@ func NewHandler(
 		assignmentService: assignmentService,
 		tripService:       tripService,
 		appealService:     appealService,
+		contractService:   contractService,
+		commentService:    commentService,
+		shiftService:      shiftService,
 		log:               log,
 	}
 }
@@ -46,6 +58,19 @@ func (h *Handler) Register(r *gin.Engine, authMiddleware gin.HandlerFunc) {
 	{
 		akimat.GET("/tickets", h.listTickets)
 		akimat.GET("/tickets/:id", h.getTicketDetails)
+		akimat.GET("/tickets/:id/assignments/history", h.getAssignmentHistory)
+		akimat.GET("/contracts", h.listContracts)
+		akimat.GET("/contracts/:id", h.getContract)
+		// Смены водителей
+		akimat.GET("/shifts", h.listShifts)
+		akimat.GET("/shifts/report", h.getShiftReport)
+		// Отчет по работе подрядчиков
+		akimat.GET("/reports/contractors", h.getContractorReport)
+		// Рассмотрение обжалований и справочник причин
+		h.registerAppealReview(akimat)
+		akimat.POST("/appeal-reasons", h.createAppealReason)
+		akimat.PUT("/appeal-reasons/:code", h.updateAppealReason)
+		h.registerTicketComments(akimat)
 	}
 
 	// KGU ZKH (TOO) - создание и управление тикетами
@@ -53,34 +78,93 @@ func (h *Handler) Register(r *gin.Engine, authMiddleware gin.HandlerFunc) {
 	{
 		kgu.GET("/tickets", h.listTickets)
 		kgu.POST("/tickets", h.createTicket)
+		kgu.POST("/tickets/emergency", h.createEmergencyTickets)
 		kgu.GET("/tickets/:id", h.getTicketDetails)
+		kgu.GET("/tickets/:id/assignments/history", h.getAssignmentHistory)
 		kgu.PUT("/tickets/:id/cancel", h.cancelTicket)
 		kgu.PUT("/tickets/:id/close", h.closeTicket)
+		kgu.PUT("/tickets/:id/priority", h.updateTicketPriority)
+		kgu.PUT("/tickets/:id/reassign", h.reassignTicket)
+		// Договоры
+		kgu.POST("/contracts", h.createContract)
+		kgu.GET("/contracts", h.listContracts)
+		kgu.GET("/contracts/:id", h.getContract)
+		kgu.GET("/reports/contractors", h.getContractorReport)
+		// Рассмотрение обжалований и распределение между инспекторами
+		kgu.GET("/appeals/queue", h.getMyAppealQueue)
+		kgu.PUT("/appeals/:id/reviewer", h.assignAppealReviewer)
+		kgu.GET("/appeal-reviewers", h.listAppealReviewers)
+		kgu.GET("/appeal-reviewers/workload", h.getAppealReviewerWorkload)
+		kgu.PUT("/appeal-reviewers/:user_id", h.saveAppealReviewer)
+		h.registerAppealReview(kgu)
+		h.registerTicketComments(kgu)
 	}
 
 	contractor := protected.Group("/contractor")
 	{
 		contractor.GET("/tickets", h.listTickets)
 		contractor.GET("/tickets/:id", h.getTicketDetails)
+		contractor.PUT("/tickets/:id/accept", h.acceptTicket)
+		contractor.PUT("/tickets/:id/decline", h.declineTicket)
 		contractor.PUT("/tickets/:id/complete", h.completeTicket)
 		// Назначения
 		contractor.POST("/tickets/:id/assignments", h.createAssignment)
 		contractor.DELETE("/assignments/:id", h.deleteAssignment)
+		contractor.PUT("/assignments/:id/swap", h.swapAssignment)
+		contractor.POST("/tickets/:id/assignments/bulk", h.bulkCreateAssignments)
+		contractor.POST("/assignments/bulk-unassign", h.bulkDeleteAssignments)
 		contractor.GET("/tickets/:id/assignments", h.listAssignments)
+		contractor.GET("/tickets/:id/assignments/history", h.getAssignmentHistory)
+		contractor.GET("/tickets/:id/assignments/suggestions", h.suggestAssignments)
+		contractor.POST("/tickets/:id/assignments/suggestions/accept", h.acceptAssignmentSuggestions)
+		// Договоры
+		contractor.GET("/contracts", h.listContracts)
+		contractor.GET("/contracts/:id", h.getContract)
+		// Смены водителей
+		contractor.POST("/shifts", h.openShift)
+		contractor.GET("/shifts", h.listShifts)
+		contractor.GET("/shifts/report", h.getShiftReport)
+		contractor.GET("/reports/contractors", h.getContractorReport)
+		contractor.PUT("/shifts/:id/close", h.closeShift)
+		// Обжалования от имени водителей
+		contractor.GET("/appeal-reasons", h.listAppealReasons)
+		contractor.POST("/appeals", h.createAppeal)
+		contractor.GET("/appeals", h.listAppeals)
+		contractor.GET("/appeals/:id", h.getAppealDetails)
+		contractor.POST("/appeals/:id/comments", h.addAppealComment)
+		contractor.GET("/appeals/:id/comments", h.getAppealComments)
+		contractor.PUT("/appeals/:id/comments/read", h.markAppealRead)
+		contractor.PUT("/appeals/:id/comments/:comment_id", h.updateAppealComment)
+		contractor.DELETE("/appeals/:id/comments/:comment_id", h.deleteAppealComment)
+		contractor.POST("/appeals/:id/attachments", h.addAppealAttachments)
+		contractor.POST("/appeals/:id/escalate", h.escalateAppeal)
+		h.registerTicketComments(contractor)
 	}
 
 	driver := protected.Group("/driver")
 	{
 		driver.GET("/tickets", h.listTickets)
 		driver.GET("/tickets/:id", h.getTicketDetails)
+		driver.GET("/tickets/:id/assignments/history", h.getAssignmentHistory)
 		// Обновление статуса водителя
+		driver.GET("/assignments", h.listMyAssignments)
 		driver.PUT("/assignments/:id/mark-in-work", h.markAssignmentInWork)
 		driver.PUT("/assignments/:id/mark-completed", h.markAssignmentCompleted)
+		// Смены
+		driver.POST("/shifts", h.openShift)
+		driver.GET("/shifts", h.listShifts)
+		driver.PUT("/shifts/:id/close", h.closeShift)
 		// Обжалования
+		driver.GET("/appeal-reasons", h.listAppealReasons)
 		driver.POST("/appeals", h.createAppeal)
 		driver.GET("/appeals", h.listMyAppeals)
-		driver.GET("/appeals/:id", h.getAppeal)
+		driver.GET("/appeals/:id", h.getAppealDetails)
 		driver.POST("/appeals/:id/comments", h.addAppealComment)
+		driver.PUT("/appeals/:id/comments/read", h.markAppealRead)
+		driver.PUT("/appeals/:id/comments/:comment_id", h.updateAppealComment)
+		driver.DELETE("/appeals/:id/comments/:comment_id", h.deleteAppealComment)
+		driver.POST("/appeals/:id/escalate", h.escalateAppeal)
+		driver.POST("/appeals/:id/attachments", h.addAppealAttachments)
 		driver.GET("/appeals/:id/comments", h.getAppealComments)
 	}
 }
@@ -99,6 +183,9 @@ func (h *Handler) createTicket(c *gin.Context) {
 		PlannedStartAt string `json:"planned_start_at" binding:"required"`
 		PlannedEndAt   string `json:"planned_end_at" binding:"required"`
 		Description    string `json:"description"`
+		Priority       string `json:"priority"`
+		AllowOverlap   bool   `json:"allow_overlap"`
+		OverlapReason  string `json:"overlap_reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -113,6 +200,9 @@ func (h *Handler) createTicket(c *gin.Context) {
 		PlannedStartAt: req.PlannedStartAt,
 		PlannedEndAt:   req.PlannedEndAt,
 		Description:    req.Description,
+		Priority:       req.Priority,
+		AllowOverlap:   req.AllowOverlap,
+		OverlapReason:  req.OverlapReason,
 	})
 	if err != nil {
 		h.handleError(c, err)
@@ -122,6 +212,93 @@ func (h *Handler) createTicket(c *gin.Context) {
 	c.JSON(http.StatusCreated, successResponse(ticket))
 }
 
+func (h *Handler) createEmergencyTickets(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	var req struct {
+		Areas []struct {
+			CleaningAreaID string `json:"cleaning_area_id" binding:"required"`
+			ContractorID   string `json:"contractor_id" binding:"required"`
+			ContractID     string `json:"contract_id" binding:"required"`
+		} `json:"areas" binding:"required,min=1,dive"`
+		PlannedStartAt string `json:"planned_start_at"`
+		PlannedEndAt   string `json:"planned_end_at"`
+		Description    string `json:"description"`
+		AllowOverlap   bool   `json:"allow_overlap"`
+		OverlapReason  string `json:"overlap_reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	input := service.CreateEmergencyTicketsInput{
+		PlannedStartAt: req.PlannedStartAt,
+		PlannedEndAt:   req.PlannedEndAt,
+		Description:    req.Description,
+		AllowOverlap:   req.AllowOverlap,
+		OverlapReason:  req.OverlapReason,
+	}
+	for _, area := range req.Areas {
+		input.Areas = append(input.Areas, service.EmergencyAreaInput{
+			CleaningAreaID: area.CleaningAreaID,
+			ContractorID:   area.ContractorID,
+			ContractID:     area.ContractID,
+		})
+	}
+
+	tickets, err := h.ticketService.CreateEmergency(c.Request.Context(), principal, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, successResponse(tickets))
+}
+
+func (h *Handler) updateTicketPriority(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	var req struct {
+		Priority string `json:"priority" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	ticket, err := h.ticketService.UpdatePriority(c.Request.Context(), principal, id, req.Priority, expectedVersion)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusOK, successResponse(ticket))
+}
+
 func (h *Handler) getTicketDetails(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
@@ -141,6 +318,7 @@ func (h *Handler) getTicketDetails(c *gin.Context) {
 		return
 	}
 
+	setETag(c, details.Ticket.Version)
 	c.JSON(http.StatusOK, successResponse(details))
 }
 
@@ -159,6 +337,46 @@ func (h *Handler) listTickets(c *gin.Context) {
 		filter.Status = &ts
 	}
 
+	priority := strings.TrimSpace(c.Query("priority"))
+	if priority != "" {
+		tp := model.TicketPriority(strings.ToUpper(priority))
+		if !tp.IsValid() {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid priority"))
+			return
+		}
+		filter.Priority = &tp
+	}
+
+	acceptance := strings.TrimSpace(c.Query("acceptance"))
+	if acceptance != "" {
+		ta := model.TicketAcceptance(strings.ToUpper(acceptance))
+		if !ta.IsValid() {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid acceptance"))
+			return
+		}
+		filter.Acceptance = &ta
+	}
+
+	escalated := strings.TrimSpace(c.Query("escalated"))
+	if escalated != "" {
+		value, err := strconv.ParseBool(escalated)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid escalated"))
+			return
+		}
+		filter.Escalated = &value
+	}
+
+	sortBy := strings.TrimSpace(c.Query("sort"))
+	switch repository.TicketSort(sortBy) {
+	case "", repository.TicketSortCreatedAt, repository.TicketSortPriority,
+		repository.TicketSortPlannedStartAt, repository.TicketSortSLADeadlineAt:
+		filter.SortBy = repository.TicketSort(sortBy)
+	default:
+		c.JSON(http.StatusBadRequest, errorResponse("invalid sort"))
+		return
+	}
+
 	contractorID := strings.TrimSpace(c.Query("contractor_id"))
 	if contractorID != "" {
 		filter.ContractorID = &contractorID
@@ -236,7 +454,13 @@ func (h *Handler) cancelTicket(c *gin.Context) {
 		return
 	}
 
-	if err := h.ticketService.Cancel(c.Request.Context(), principal, id); err != nil {
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	if err := h.ticketService.Cancel(c.Request.Context(), principal, id, expectedVersion); err != nil {
 		h.handleError(c, err)
 		return
 	}
@@ -257,7 +481,13 @@ func (h *Handler) closeTicket(c *gin.Context) {
 		return
 	}
 
-	if err := h.ticketService.Close(c.Request.Context(), principal, id); err != nil {
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	if err := h.ticketService.Close(c.Request.Context(), principal, id, expectedVersion); err != nil {
 		h.handleError(c, err)
 		return
 	}
@@ -265,6 +495,115 @@ func (h *Handler) closeTicket(c *gin.Context) {
 	c.JSON(http.StatusOK, successResponse(gin.H{"message": "ticket closed"}))
 }
 
+func (h *Handler) acceptTicket(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	ticket, err := h.ticketService.Accept(c.Request.Context(), principal, id, expectedVersion)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusOK, successResponse(ticket))
+}
+
+func (h *Handler) declineTicket(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	ticket, err := h.ticketService.Decline(c.Request.Context(), principal, id, req.Reason, expectedVersion)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusOK, successResponse(ticket))
+}
+
+func (h *Handler) reassignTicket(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	var req struct {
+		ContractorID string `json:"contractor_id" binding:"required"`
+		ContractID   string `json:"contract_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	ticket, err := h.ticketService.Reassign(c.Request.Context(), principal, id, service.ReassignTicketInput{
+		ContractorID: req.ContractorID,
+		ContractID:   req.ContractID,
+	}, expectedVersion)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusOK, successResponse(ticket))
+}
+
 func (h *Handler) completeTicket(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
@@ -278,7 +617,13 @@ func (h *Handler) completeTicket(c *gin.Context) {
 		return
 	}
 
-	if err := h.ticketService.Complete(c.Request.Context(), principal, id); err != nil {
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	if err := h.ticketService.Complete(c.Request.Context(), principal, id, expectedVersion); err != nil {
 		h.handleError(c, err)
 		return
 	}
@@ -301,8 +646,10 @@ func (h *Handler) createAssignment(c *gin.Context) {
 	}
 
 	var req struct {
-		DriverID  string `json:"driver_id" binding:"required"`
-		VehicleID string `json:"vehicle_id" binding:"required"`
+		DriverID      string `json:"driver_id" binding:"required"`
+		VehicleID     string `json:"vehicle_id" binding:"required"`
+		AllowOverlap  bool   `json:"allow_overlap"`
+		OverlapReason string `json:"overlap_reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -311,9 +658,11 @@ func (h *Handler) createAssignment(c *gin.Context) {
 	}
 
 	assignment, err := h.assignmentService.Create(c.Request.Context(), principal, service.CreateAssignmentInput{
-		TicketID:  ticketID,
-		DriverID:  req.DriverID,
-		VehicleID: req.VehicleID,
+		TicketID:      ticketID,
+		DriverID:      req.DriverID,
+		VehicleID:     req.VehicleID,
+		AllowOverlap:  req.AllowOverlap,
+		OverlapReason: req.OverlapReason,
 	})
 	if err != nil {
 		h.handleError(c, err)
@@ -336,7 +685,16 @@ func (h *Handler) deleteAssignment(c *gin.Context) {
 		return
 	}
 
-	if err := h.assignmentService.Delete(c.Request.Context(), principal, id); err != nil {
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	// Причина снятия передается необязательным query-параметром
+	reason := c.Query("reason")
+
+	if err := h.assignmentService.Delete(c.Request.Context(), principal, id, reason, expectedVersion); err != nil {
 		h.handleError(c, err)
 		return
 	}
@@ -344,6 +702,210 @@ func (h *Handler) deleteAssignment(c *gin.Context) {
 	c.JSON(http.StatusOK, successResponse(gin.H{"message": "assignment deleted"}))
 }
 
+func (h *Handler) bulkCreateAssignments(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	ticketID := strings.TrimSpace(c.Param("id"))
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	var req struct {
+		Items []struct {
+			DriverID      string `json:"driver_id" binding:"required"`
+			VehicleID     string `json:"vehicle_id" binding:"required"`
+			AllowOverlap  bool   `json:"allow_overlap"`
+			OverlapReason string `json:"overlap_reason"`
+		} `json:"items" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	items := make([]service.BulkAssignmentItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, service.BulkAssignmentItem{
+			DriverID:      item.DriverID,
+			VehicleID:     item.VehicleID,
+			AllowOverlap:  item.AllowOverlap,
+			OverlapReason: item.OverlapReason,
+		})
+	}
+
+	result, err := h.assignmentService.BulkCreate(c.Request.Context(), principal, ticketID, items)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, successResponse(result))
+}
+
+func (h *Handler) bulkDeleteAssignments(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	var req struct {
+		Items []struct {
+			AssignmentID string `json:"assignment_id" binding:"required"`
+			Version      *int64 `json:"version"`
+		} `json:"items" binding:"required,min=1,dive"`
+		Reason string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	items := make([]service.BulkUnassignItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, service.BulkUnassignItem{
+			AssignmentID:    item.AssignmentID,
+			ExpectedVersion: item.Version,
+		})
+	}
+
+	result, err := h.assignmentService.BulkDelete(c.Request.Context(), principal, items, req.Reason)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(result))
+}
+
+func (h *Handler) suggestAssignments(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	ticketID := strings.TrimSpace(c.Param("id"))
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	count := 1
+	if raw := strings.TrimSpace(c.Query("count")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid count"))
+			return
+		}
+		count = parsed
+	}
+
+	suggestions, err := h.assignmentService.Suggest(c.Request.Context(), principal, ticketID, count)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(suggestions))
+}
+
+func (h *Handler) acceptAssignmentSuggestions(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	ticketID := strings.TrimSpace(c.Param("id"))
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	var req struct {
+		Pairs []struct {
+			DriverID  string `json:"driver_id" binding:"required"`
+			VehicleID string `json:"vehicle_id" binding:"required"`
+		} `json:"pairs" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	pairs := make([]service.AssignmentPairInput, 0, len(req.Pairs))
+	for _, p := range req.Pairs {
+		pairs = append(pairs, service.AssignmentPairInput{DriverID: p.DriverID, VehicleID: p.VehicleID})
+	}
+
+	assignments, err := h.assignmentService.AcceptSuggestions(c.Request.Context(), principal, ticketID, pairs)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, successResponse(assignments))
+}
+
+func (h *Handler) swapAssignment(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid assignment id"))
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	var req struct {
+		DriverID      string `json:"driver_id"`
+		VehicleID     string `json:"vehicle_id"`
+		At            string `json:"at"`
+		Reason        string `json:"reason"`
+		AllowOverlap  bool   `json:"allow_overlap"`
+		OverlapReason string `json:"overlap_reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	assignment, err := h.assignmentService.Swap(c.Request.Context(), principal, id, service.SwapAssignmentInput{
+		DriverID:      strings.TrimSpace(req.DriverID),
+		VehicleID:     strings.TrimSpace(req.VehicleID),
+		At:            strings.TrimSpace(req.At),
+		Reason:        req.Reason,
+		AllowOverlap:  req.AllowOverlap,
+		OverlapReason: req.OverlapReason,
+	}, expectedVersion)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setETag(c, assignment.Version)
+	c.JSON(http.StatusCreated, successResponse(assignment))
+}
+
 func (h *Handler) listAssignments(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
@@ -366,6 +928,44 @@ func (h *Handler) listAssignments(c *gin.Context) {
 	c.JSON(http.StatusOK, successResponse(assignments))
 }
 
+func (h *Handler) getAssignmentHistory(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	ticketID := strings.TrimSpace(c.Param("id"))
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	history, err := h.assignmentService.History(c.Request.Context(), principal, ticketID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(history))
+}
+
+func (h *Handler) listMyAssignments(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	assignments, err := h.assignmentService.ListMine(c.Request.Context(), principal)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(assignments))
+}
+
 func (h *Handler) markAssignmentInWork(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
@@ -379,7 +979,19 @@ func (h *Handler) markAssignmentInWork(c *gin.Context) {
 		return
 	}
 
-	if err := h.assignmentService.UpdateDriverMarkStatus(c.Request.Context(), principal, id, model.DriverMarkStatusInWork); err != nil {
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	location, ok := markLocation(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid location: lat and lon must be provided together"))
+		return
+	}
+
+	if err := h.assignmentService.UpdateDriverMarkStatus(c.Request.Context(), principal, id, model.DriverMarkStatusInWork, location, expectedVersion); err != nil {
 		h.handleError(c, err)
 		return
 	}
@@ -400,7 +1012,19 @@ func (h *Handler) markAssignmentCompleted(c *gin.Context) {
 		return
 	}
 
-	if err := h.assignmentService.UpdateDriverMarkStatus(c.Request.Context(), principal, id, model.DriverMarkStatusCompleted); err != nil {
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid If-Match header"))
+		return
+	}
+
+	location, ok := markLocation(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid location: lat and lon must be provided together"))
+		return
+	}
+
+	if err := h.assignmentService.UpdateDriverMarkStatus(c.Request.Context(), principal, id, model.DriverMarkStatusCompleted, location, expectedVersion); err != nil {
 		h.handleError(c, err)
 		return
 	}
@@ -420,6 +1044,8 @@ func (h *Handler) createAppeal(c *gin.Context) {
 		TripID          string `json:"trip_id" binding:"required"`
 		AppealReasonType string `json:"appeal_reason_type" binding:"required"`
 		Comment         string `json:"comment" binding:"required"`
+		Attachments     []attachmentRequest `json:"attachments" binding:"omitempty,dive"`
+		DriverID        string `json:"driver_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -431,6 +1057,8 @@ func (h *Handler) createAppeal(c *gin.Context) {
 		TripID:          req.TripID,
 		AppealReasonType: req.AppealReasonType,
 		Comment:         req.Comment,
+		Attachments:     toAttachmentInputs(req.Attachments),
+		DriverID:        req.DriverID,
 	})
 	if err != nil {
 		h.handleError(c, err)
@@ -459,12 +1087,36 @@ func (h *Handler) listMyAppeals(c *gin.Context) {
 		return
 	}
 
-	// Если ticket_id не указан, возвращаем все обжалования водителя
-	// (это можно реализовать через отдельный метод в сервисе, но пока используем существующий)
-	c.JSON(http.StatusBadRequest, errorResponse("ticket_id is required for now"))
+	// Без ticket_id - входящие водителя по всем его обжалованиям
+	var page, pageSize int
+	if raw := strings.TrimSpace(c.Query("page")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid page"))
+			return
+		}
+		page = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("page_size")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, errorResponse("invalid page_size"))
+			return
+		}
+		pageSize = parsed
+	}
+	status := strings.TrimSpace(c.Query("status"))
+
+	inbox, err := h.appealService.ListMine(c.Request.Context(), principal, status, page, pageSize)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(inbox))
 }
 
-func (h *Handler) getAppeal(c *gin.Context) {
+func (h *Handler) markAppealRead(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
@@ -477,13 +1129,12 @@ func (h *Handler) getAppeal(c *gin.Context) {
 		return
 	}
 
-	appeal, err := h.appealService.GetByID(c.Request.Context(), principal, id)
-	if err != nil {
+	if err := h.appealService.MarkRead(c.Request.Context(), principal, id); err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, successResponse(appeal))
+	c.JSON(http.StatusOK, successResponse(gin.H{"message": "marked as read"}))
 }
 
 func (h *Handler) addAppealComment(c *gin.Context) {
@@ -500,7 +1151,9 @@ func (h *Handler) addAppealComment(c *gin.Context) {
 	}
 
 	var req struct {
-		Content string `json:"content" binding:"required"`
+		Content     string              `json:"content" binding:"required"`
+		Visibility  string              `json:"visibility"`
+		Attachments []attachmentRequest `json:"attachments" binding:"omitempty,dive"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -508,12 +1161,14 @@ func (h *Handler) addAppealComment(c *gin.Context) {
 		return
 	}
 
-	if err := h.appealService.AddComment(c.Request.Context(), principal, id, req.Content); err != nil {
+	visibility := model.AppealCommentVisibility(strings.ToUpper(strings.TrimSpace(req.Visibility)))
+	comment, err := h.appealService.AddComment(c.Request.Context(), principal, id, req.Content, visibility, toAttachmentInputs(req.Attachments))
+	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, successResponse(gin.H{"message": "comment added"}))
+	c.JSON(http.StatusCreated, successResponse(comment))
 }
 
 func (h *Handler) getAppealComments(c *gin.Context) {
@@ -538,8 +1193,68 @@ func (h *Handler) getAppealComments(c *gin.Context) {
 	c.JSON(http.StatusOK, successResponse(comments))
 }
 
+func (h *Handler) updateAppealComment(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	commentID := strings.TrimSpace(c.Param("comment_id"))
+	if id == "" || commentID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid comment id"))
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	comment, err := h.appealService.UpdateComment(c.Request.Context(), principal, id, commentID, req.Content)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(comment))
+}
+
+func (h *Handler) deleteAppealComment(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	commentID := strings.TrimSpace(c.Param("comment_id"))
+	if id == "" || commentID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid comment id"))
+		return
+	}
+
+	if err := h.appealService.DeleteComment(c.Request.Context(), principal, id, commentID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(gin.H{"message": "comment deleted"}))
+}
+
 func (h *Handler) handleError(c *gin.Context, err error) {
+	var conflictErr *service.ConflictError
 	switch {
+	case errors.As(err, &conflictErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   conflictErr.Message,
+			"details": conflictErr.Details,
+		})
 	case errors.Is(err, service.ErrPermissionDenied):
 		c.JSON(http.StatusForbidden, errorResponse(err.Error()))
 	case errors.Is(err, service.ErrNotFound):
@@ -548,12 +1263,54 @@ func (h *Handler) handleError(c *gin.Context, err error) {
 		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
 	case errors.Is(err, service.ErrConflict):
 		c.JSON(http.StatusConflict, errorResponse(err.Error()))
+	case errors.Is(err, service.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, errorResponse(err.Error()))
 	default:
 		h.log.Error().Err(err).Msg("handler error")
 		c.JSON(http.StatusInternalServerError, errorResponse("internal error"))
 	}
 }
 
+// ifMatchVersion извлекает ожидаемую версию записи из заголовка If-Match.
+// Отсутствующий заголовок или "*" означают отсутствие проверки.
+func ifMatchVersion(c *gin.Context) (*int64, bool) {
+	raw := strings.TrimSpace(c.GetHeader("If-Match"))
+	if raw == "" || raw == "*" {
+		return nil, true
+	}
+
+	raw = strings.TrimPrefix(raw, "W/")
+	raw = strings.Trim(raw, `"`)
+	version, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &version, true
+}
+
+// markLocation читает необязательные координаты водителя из тела запроса отметки
+func markLocation(c *gin.Context) (*service.MarkLocation, bool) {
+	var req struct {
+		Lat *float64 `json:"lat"`
+		Lon *float64 `json:"lon"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// Тело отметки необязательно
+		return nil, errors.Is(err, io.EOF)
+	}
+	if req.Lat == nil && req.Lon == nil {
+		return nil, true
+	}
+	if req.Lat == nil || req.Lon == nil {
+		return nil, false
+	}
+	return &service.MarkLocation{Lat: *req.Lat, Lon: *req.Lon}, true
+}
+
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
 func successResponse(data interface{}) gin.H {
 	return gin.H{
 		"data": data,
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ticket-service/internal/http/middleware"
+	"ticket-service/internal/service"
+)
+
+func (h *Handler) getContractorReport(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	report, err := h.ticketService.ContractorReport(c.Request.Context(), principal, service.ContractorReportInput{
+		ContractorID:   c.Query("contractor_id"),
+		CleaningAreaID: c.Query("cleaning_area_id"),
+		ContractID:     c.Query("contract_id"),
+		From:           c.Query("from"),
+		To:             c.Query("to"),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(report))
+}
@@ -18,8 +18,8 @@ func NewRouter(handler *Handler, authMiddleware gin.HandlerFunc, env string) *gi
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Type"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "If-Match"},
+		ExposeHeaders:    []string{"Content-Type", "ETag"},
 		MaxAge:           12 * time.Hour,
 	}))
 
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ticket-service/internal/http/middleware"
+	"ticket-service/internal/service"
+)
+
+func (h *Handler) openShift(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	// Водитель открывает смену себе без тела запроса, подрядчик передает driver_id
+	var req struct {
+		DriverID string `json:"driver_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	shift, err := h.shiftService.Open(c.Request.Context(), principal, strings.TrimSpace(req.DriverID))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, successResponse(shift))
+}
+
+func (h *Handler) closeShift(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid shift id"))
+		return
+	}
+
+	shift, err := h.shiftService.Close(c.Request.Context(), principal, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(shift))
+}
+
+func (h *Handler) listShifts(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	shifts, err := h.shiftService.List(c.Request.Context(), principal, service.ListShiftsInput{
+		ContractorID: strings.TrimSpace(c.Query("contractor_id")),
+		DriverID:     strings.TrimSpace(c.Query("driver_id")),
+		From:         strings.TrimSpace(c.Query("from")),
+		To:           strings.TrimSpace(c.Query("to")),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(shifts))
+}
+
+func (h *Handler) getShiftReport(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	report, err := h.shiftService.Report(
+		c.Request.Context(),
+		principal,
+		strings.TrimSpace(c.Query("contractor_id")),
+		strings.TrimSpace(c.Query("from")),
+		strings.TrimSpace(c.Query("to")),
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(report))
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ticket-service/internal/http/middleware"
+	"ticket-service/internal/service"
+)
+
+// registerTicketComments подключает обсуждение тикета к группе роли
+func (h *Handler) registerTicketComments(group *gin.RouterGroup) {
+	group.GET("/tickets/:id/comments", h.listTicketComments)
+	group.POST("/tickets/:id/comments", h.addTicketComment)
+	group.PUT("/tickets/:id/comments/read", h.markTicketCommentsRead)
+	group.PUT("/tickets/:id/comments/:comment_id", h.updateTicketComment)
+	group.DELETE("/tickets/:id/comments/:comment_id", h.deleteTicketComment)
+}
+
+func (h *Handler) listTicketComments(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	comments, err := h.commentService.List(c.Request.Context(), principal, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(comments))
+}
+
+func (h *Handler) addTicketComment(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	var req struct {
+		Content          string   `json:"content" binding:"required"`
+		MentionedUserIDs []string `json:"mentioned_user_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	comment, err := h.commentService.Create(c.Request.Context(), principal, id, service.CreateTicketCommentInput{
+		Content:          req.Content,
+		MentionedUserIDs: req.MentionedUserIDs,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, successResponse(comment))
+}
+
+func (h *Handler) updateTicketComment(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	commentID := strings.TrimSpace(c.Param("comment_id"))
+	if id == "" || commentID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid comment id"))
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	comment, err := h.commentService.Update(c.Request.Context(), principal, id, commentID, req.Content)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(comment))
+}
+
+func (h *Handler) deleteTicketComment(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	commentID := strings.TrimSpace(c.Param("comment_id"))
+	if id == "" || commentID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid comment id"))
+		return
+	}
+
+	if err := h.commentService.Delete(c.Request.Context(), principal, id, commentID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(gin.H{"message": "comment deleted"}))
+}
+
+func (h *Handler) markTicketCommentsRead(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		return
+	}
+
+	if err := h.commentService.MarkRead(c.Request.Context(), principal, id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse(gin.H{"message": "comments marked as read"}))
+}
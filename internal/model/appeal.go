@@ -18,17 +18,67 @@ const (
 	AppealStatusClosed      AppealStatus = "CLOSED"
 )
 
+// IsValid проверяет, что статус обжалования входит в допустимый набор значений
+func (s AppealStatus) IsValid() bool {
+	switch s {
+	case AppealStatusSubmitted, AppealStatusUnderReview, AppealStatusNeedInfo,
+		AppealStatusApproved, AppealStatusRejected, AppealStatusClosed:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo проверяет допустимость перехода при рассмотрении обжалования
+func (s AppealStatus) CanTransitionTo(next AppealStatus) bool {
+	switch s {
+	case AppealStatusSubmitted:
+		return next == AppealStatusUnderReview || next == AppealStatusClosed
+	case AppealStatusUnderReview:
+		return next == AppealStatusNeedInfo || next == AppealStatusApproved || next == AppealStatusRejected
+	case AppealStatusNeedInfo:
+		return next == AppealStatusUnderReview || next == AppealStatusClosed
+	case AppealStatusApproved, AppealStatusRejected:
+		return next == AppealStatusClosed
+	default:
+		return false
+	}
+}
+
+// IsResolution сообщает, что статус завершает рассмотрение обжалования
+func (s AppealStatus) IsResolution() bool {
+	return s == AppealStatusApproved || s == AppealStatusRejected || s == AppealStatusClosed
+}
+
 type Appeal struct {
 	ID              uuid.UUID    `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
 	TripID          *uuid.UUID   `gorm:"type:uuid;index" json:"trip_id"`
 	TicketID        *uuid.UUID   `gorm:"type:uuid;index" json:"ticket_id"`
 	CreatedByUserID uuid.UUID    `gorm:"type:uuid;not null" json:"created_by_user_id"`
+	// CreatedByRole - кто подал обжалование: водитель или подрядчик от имени водителя
+	CreatedByRole   UserRole     `gorm:"type:varchar(32);not null" json:"created_by_role"`
+	// DriverID - водитель, чей рейс обжалуется (может быть не известен для рейса без назначения)
+	DriverID        *uuid.UUID   `gorm:"type:uuid;index" json:"driver_id"`
 	Status          AppealStatus `gorm:"type:appeal_status;not null;default:SUBMITTED" json:"status"`
 	Reason          string       `gorm:"type:text;not null" json:"reason"`
 	AppealReasonType *string     `gorm:"type:varchar(50)" json:"appeal_reason_type"`
 	Comment         string       `gorm:"type:text;not null" json:"comment"`
 	AdminResponse   *string      `gorm:"type:text" json:"admin_response"`
 	ResolvedAt      *time.Time   `json:"resolved_at"`
+	// ReviewDueAt - срок рассмотрения КГУ, ResponseDueAt - срок ответа водителя на запрос информации
+	ReviewDueAt     *time.Time   `json:"review_due_at"`
+	ResponseDueAt   *time.Time   `json:"response_due_at"`
+	// EscalatedAt - когда рассмотрение передано Акимату (по просрочке или по обжалованию отказа)
+	EscalatedAt     *time.Time   `json:"escalated_at"`
+	// Decision - вынесенное решение (APPROVED или REJECTED), сохраняется и после закрытия
+	Decision        *AppealStatus `gorm:"type:appeal_status" json:"decision"`
+	// ParentAppealID - обжалование, отказ по которому обжалуется в Акимате
+	ParentAppealID  *uuid.UUID   `gorm:"type:uuid" json:"parent_appeal_id"`
+	// ReviewerID - инспектор КГУ, которому назначено рассмотрение
+	ReviewerID      *uuid.UUID   `gorm:"type:uuid" json:"reviewer_id"`
+	ReviewerAssignedAt *time.Time `json:"reviewer_assigned_at"`
+	// Attachments - вложения самого обжалования (без вложений комментариев)
+	Attachments     []AppealAttachment `gorm:"-" json:"attachments,omitempty"`
+	Version         int64        `gorm:"not null;default:1" json:"version"`
 	CreatedAt       time.Time    `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt       time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
 }
@@ -41,15 +91,40 @@ func (a *Appeal) BeforeCreate(tx *gorm.DB) error {
 	if a.ID == uuid.Nil {
 		a.ID = uuid.New()
 	}
+	if a.Version == 0 {
+		a.Version = 1
+	}
 	return nil
 }
 
+// AppealCommentVisibility - кому виден комментарий к обжалованию
+type AppealCommentVisibility string
+
+const (
+	// AppealCommentPublic - виден всем участникам обжалования
+	AppealCommentPublic   AppealCommentVisibility = "PUBLIC"
+	// AppealCommentInternal - внутренняя заметка, видна только КГУ и Акимату
+	AppealCommentInternal AppealCommentVisibility = "INTERNAL"
+)
+
+// IsValid проверяет, что видимость входит в допустимый набор значений
+func (v AppealCommentVisibility) IsValid() bool {
+	return v == AppealCommentPublic || v == AppealCommentInternal
+}
+
 type AppealComment struct {
 	ID             uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
 	AppealID       uuid.UUID `gorm:"type:uuid;not null;index" json:"appeal_id"`
 	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null" json:"created_by_user_id"`
 	Content        string    `gorm:"type:text;not null" json:"content"`
+	Visibility     AppealCommentVisibility `gorm:"type:varchar(16);not null;default:PUBLIC" json:"visibility"`
+	Attachments    []AppealAttachment `gorm:"foreignKey:CommentID" json:"attachments"`
+	// Edits - история правок, отдается только КГУ и Акимату
+	Edits          []AppealCommentEdit `gorm:"foreignKey:CommentID" json:"edits,omitempty"`
+	EditedAt       *time.Time `json:"edited_at"`
+	DeletedAt      *time.Time `json:"deleted_at"`
 	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (AppealComment) TableName() string {
@@ -63,3 +138,94 @@ func (ac *AppealComment) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// AppealCommentAction - вид правки комментария
+type AppealCommentAction string
+
+const (
+	AppealCommentEdited  AppealCommentAction = "EDITED"
+	AppealCommentDeleted AppealCommentAction = "DELETED"
+)
+
+// AppealCommentEdit - запись аудита правки или удаления комментария с прежним текстом
+type AppealCommentEdit struct {
+	ID              uuid.UUID           `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	CommentID       uuid.UUID           `gorm:"type:uuid;not null;index" json:"comment_id"`
+	Action          AppealCommentAction `gorm:"type:varchar(16);not null" json:"action"`
+	PreviousContent string              `gorm:"type:text;not null" json:"previous_content"`
+	EditedByUserID  uuid.UUID           `gorm:"type:uuid;not null" json:"edited_by_user_id"`
+	CreatedAt       time.Time           `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AppealCommentEdit) TableName() string {
+	return "appeal_comment_edits"
+}
+
+func (e *AppealCommentEdit) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// AppealRead - отметка о прочтении переписки по обжалованию пользователем
+type AppealRead struct {
+	AppealID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"appeal_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	LastReadAt time.Time `gorm:"not null" json:"last_read_at"`
+}
+
+func (AppealRead) TableName() string {
+	return "appeal_reads"
+}
+
+// AttachmentKind - вид вложения, определяется по типу содержимого
+type AttachmentKind string
+
+const (
+	AttachmentKindPhoto    AttachmentKind = "PHOTO"
+	AttachmentKindVideo    AttachmentKind = "VIDEO"
+	AttachmentKindDocument AttachmentKind = "DOCUMENT"
+)
+
+// attachmentKinds - допустимые типы содержимого вложений
+var attachmentKinds = map[string]AttachmentKind{
+	"image/jpeg":      AttachmentKindPhoto,
+	"image/png":       AttachmentKindPhoto,
+	"image/webp":      AttachmentKindPhoto,
+	"image/heic":      AttachmentKindPhoto,
+	"video/mp4":       AttachmentKindVideo,
+	"video/quicktime": AttachmentKindVideo,
+	"application/pdf": AttachmentKindDocument,
+}
+
+// AttachmentKindOf возвращает вид вложения для типа содержимого; false - тип не допускается
+func AttachmentKindOf(contentType string) (AttachmentKind, bool) {
+	kind, ok := attachmentKinds[contentType]
+	return kind, ok
+}
+
+// AppealAttachment - файл-доказательство к обжалованию или к комментарию обжалования.
+// Сам файл хранится во внешнем хранилище, здесь - ссылка и метаданные
+type AppealAttachment struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	AppealID         uuid.UUID      `gorm:"type:uuid;not null;index" json:"appeal_id"`
+	CommentID        *uuid.UUID     `gorm:"type:uuid;index" json:"comment_id"`
+	Kind             AttachmentKind `gorm:"type:varchar(16);not null" json:"kind"`
+	URL              string         `gorm:"type:text;not null" json:"url"`
+	FileName         string         `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType      string         `gorm:"type:varchar(100);not null" json:"content_type"`
+	SizeBytes        int64          `gorm:"not null" json:"size_bytes"`
+	UploadedByUserID uuid.UUID      `gorm:"type:uuid;not null" json:"uploaded_by_user_id"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AppealAttachment) TableName() string {
+	return "appeal_attachments"
+}
+
+func (a *AppealAttachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AppealReasonType - причина обжалования из справочника, который ведет Акимат
+type AppealReasonType struct {
+	Code             string                   `gorm:"type:varchar(50);primaryKey" json:"code"`
+	TitleRu          string                   `gorm:"type:varchar(255);not null" json:"title_ru"`
+	TitleKk          string                   `gorm:"type:varchar(255);not null" json:"title_kk"`
+	TripStatuses     []AppealReasonTripStatus `gorm:"foreignKey:ReasonCode;references:Code" json:"trip_statuses"`
+	EvidenceRequired bool                     `gorm:"not null" json:"evidence_required"`
+	IsActive         bool                     `gorm:"not null" json:"is_active"`
+	Version          int64                    `gorm:"not null;default:1" json:"version"`
+	CreatedAt        time.Time                `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time                `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (AppealReasonType) TableName() string {
+	return "appeal_reason_types"
+}
+
+func (r *AppealReasonType) BeforeCreate(tx *gorm.DB) error {
+	if r.Version == 0 {
+		r.Version = 1
+	}
+	return nil
+}
+
+// Contests сообщает, можно ли по этой причине обжаловать рейс с данным статусом
+func (r *AppealReasonType) Contests(status TripStatus) bool {
+	for _, s := range r.TripStatuses {
+		if s.TripStatus == status {
+			return true
+		}
+	}
+	return false
+}
+
+// AppealReasonTripStatus - статус рейса, который можно обжаловать по причине
+type AppealReasonTripStatus struct {
+	ReasonCode string     `gorm:"type:varchar(50);primaryKey" json:"-"`
+	TripStatus TripStatus `gorm:"type:trip_status;primaryKey" json:"trip_status"`
+}
+
+func (AppealReasonTripStatus) TableName() string {
+	return "appeal_reason_trip_statuses"
+}
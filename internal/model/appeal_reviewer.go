@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AppealReviewer - инспектор КГУ, рассматривающий обжалования по тикетам своей организации.
+// LastAssignedAt используется для распределения по очереди
+type AppealReviewer struct {
+	OrgID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"org_id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	IsActive       bool       `gorm:"not null" json:"is_active"`
+	LastAssignedAt *time.Time `json:"last_assigned_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (AppealReviewer) TableName() string {
+	return "appeal_reviewers"
+}
+
+// AppealReviewerAssignment - запись о назначении (переназначении) рассматривающего обжалование.
+// AssignedByUserID пуст при автоматическом распределении
+type AppealReviewerAssignment struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	AppealID           uuid.UUID  `gorm:"type:uuid;not null;index" json:"appeal_id"`
+	ReviewerID         uuid.UUID  `gorm:"type:uuid;not null" json:"reviewer_id"`
+	PreviousReviewerID *uuid.UUID `gorm:"type:uuid" json:"previous_reviewer_id"`
+	AssignedByUserID   *uuid.UUID `gorm:"type:uuid" json:"assigned_by_user_id"`
+	Reason             *string    `gorm:"type:text" json:"reason"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AppealReviewerAssignment) TableName() string {
+	return "appeal_reviewer_assignments"
+}
+
+func (a *AppealReviewerAssignment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
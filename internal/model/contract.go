@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Contract - договор КГУ с подрядчиком: период, лимит объема и тариф за 1 м³
+type Contract struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	Number         string    `gorm:"type:varchar(100);not null" json:"number"`
+	ContractorID   uuid.UUID `gorm:"type:uuid;not null;index" json:"contractor_id"`
+	CreatedByOrgID uuid.UUID `gorm:"type:uuid;not null;index" json:"created_by_org_id"`
+	StartsAt       time.Time `gorm:"not null" json:"starts_at"`
+	EndsAt         time.Time `gorm:"not null" json:"ends_at"`
+	MaxVolumeM3    float64   `gorm:"not null" json:"max_volume_m3"`
+	UnitTariff     float64   `gorm:"not null" json:"unit_tariff"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Contract) TableName() string {
+	return "contracts"
+}
+
+func (c *Contract) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// Covers проверяет, что плановое окно целиком попадает в период договора
+func (c *Contract) Covers(start, end time.Time) bool {
+	return !start.Before(c.StartsAt) && !end.After(c.EndsAt)
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DriverShift - смена водителя. Открытая смена не имеет EndedAt
+type DriverShift struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	DriverID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"driver_id"`
+	ContractorID uuid.UUID  `gorm:"type:uuid;not null;index" json:"contractor_id"`
+	StartedAt    time.Time  `gorm:"not null" json:"started_at"`
+	EndedAt      *time.Time `json:"ended_at"`
+	OpenedBy     uuid.UUID  `gorm:"type:uuid;not null" json:"opened_by"`
+	ClosedBy     *uuid.UUID `gorm:"type:uuid" json:"closed_by,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (DriverShift) TableName() string {
+	return "driver_shifts"
+}
+
+func (s *DriverShift) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	if s.StartedAt.IsZero() {
+		s.StartedAt = time.Now()
+	}
+	return nil
+}
+
+// IsOpen сообщает, что смена еще не закрыта
+func (s *DriverShift) IsOpen() bool {
+	return s.EndedAt == nil
+}
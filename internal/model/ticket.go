@@ -17,23 +17,69 @@ const (
 	TicketStatusCancelled  TicketStatus = "CANCELLED"
 )
 
+type TicketPriority string
+
+const (
+	TicketPriorityEmergency TicketPriority = "EMERGENCY"
+	TicketPriorityHigh      TicketPriority = "HIGH"
+	TicketPriorityNormal    TicketPriority = "NORMAL"
+)
+
+// IsValid проверяет, что приоритет входит в допустимый набор значений
+func (p TicketPriority) IsValid() bool {
+	switch p {
+	case TicketPriorityEmergency, TicketPriorityHigh, TicketPriorityNormal:
+		return true
+	}
+	return false
+}
+
+// TicketAcceptance - подтверждение тикета подрядчиком
+type TicketAcceptance string
+
+const (
+	TicketAcceptancePending  TicketAcceptance = "PENDING"
+	TicketAcceptanceAccepted TicketAcceptance = "ACCEPTED"
+	TicketAcceptanceDeclined TicketAcceptance = "DECLINED"
+)
+
+// IsValid проверяет, что статус подтверждения входит в допустимый набор значений
+func (a TicketAcceptance) IsValid() bool {
+	switch a {
+	case TicketAcceptancePending, TicketAcceptanceAccepted, TicketAcceptanceDeclined:
+		return true
+	}
+	return false
+}
+
 type Ticket struct {
-	ID             uuid.UUID    `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
-	CleaningAreaID uuid.UUID    `gorm:"type:uuid;not null;index" json:"cleaning_area_id"`
-	ContractorID   uuid.UUID    `gorm:"type:uuid;not null;index" json:"contractor_id"`
-	ContractID     *uuid.UUID   `gorm:"type:uuid;index" json:"contract_id"`
-	CreatedByOrgID uuid.UUID    `gorm:"type:uuid;not null;index" json:"created_by_org_id"`
-	Status         TicketStatus `gorm:"type:ticket_status;not null;default:PLANNED" json:"status"`
-	PlannedStartAt time.Time    `gorm:"not null" json:"planned_start_at"`
-	PlannedEndAt   time.Time    `gorm:"not null" json:"planned_end_at"`
-	FactStartAt    *time.Time   `json:"fact_start_at"`
-	FactEndAt      *time.Time   `json:"fact_end_at"`
-	Description    string       `gorm:"type:text" json:"description"`
-	PhotoURL       *string      `gorm:"type:text" json:"photo_url"`
-	Latitude       *float64     `json:"latitude"`
-	Longitude      *float64     `json:"longitude"`
-	CreatedAt      time.Time    `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt      time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+	ID             uuid.UUID        `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	CleaningAreaID uuid.UUID        `gorm:"type:uuid;not null;index" json:"cleaning_area_id"`
+	ContractorID   uuid.UUID        `gorm:"type:uuid;not null;index" json:"contractor_id"`
+	ContractID     *uuid.UUID       `gorm:"type:uuid;index" json:"contract_id"`
+	CreatedByOrgID uuid.UUID        `gorm:"type:uuid;not null;index" json:"created_by_org_id"`
+	Status         TicketStatus     `gorm:"type:ticket_status;not null;default:PLANNED" json:"status"`
+	Priority       TicketPriority   `gorm:"type:ticket_priority;not null;default:NORMAL" json:"priority"`
+	SLADeadlineAt  *time.Time       `json:"sla_deadline_at"`
+	Acceptance     TicketAcceptance `gorm:"type:ticket_acceptance;not null;default:PENDING" json:"acceptance"`
+	AcceptDueAt    *time.Time       `json:"accept_due_at"`
+	AcceptedAt     *time.Time       `json:"accepted_at"`
+	DeclinedAt     *time.Time       `json:"declined_at"`
+	DeclineReason  *string          `gorm:"type:text" json:"decline_reason"`
+	EscalatedAt    *time.Time       `json:"escalated_at"`
+	PlannedStartAt time.Time        `gorm:"not null" json:"planned_start_at"`
+	PlannedEndAt   time.Time        `gorm:"not null" json:"planned_end_at"`
+	FactStartAt    *time.Time       `json:"fact_start_at"`
+	FactEndAt      *time.Time       `json:"fact_end_at"`
+	Description    string           `gorm:"type:text" json:"description"`
+	OverlapReason  *string          `gorm:"type:text" json:"overlap_reason,omitempty"` // обоснование параллельных работ на участке
+	PhotoURL       *string          `gorm:"type:text" json:"photo_url"`
+	Latitude       *float64         `json:"latitude"`
+	Longitude      *float64         `json:"longitude"`
+	Version        int64            `gorm:"not null;default:1" json:"version"`
+	CreatedAt      time.Time        `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time        `gorm:"autoUpdateTime" json:"updated_at"`
+	UnreadComments *int64           `gorm:"-" json:"unread_comments,omitempty"` // заполняется в списках тикетов
 }
 
 func (Ticket) TableName() string {
@@ -44,6 +90,9 @@ func (t *Ticket) BeforeCreate(tx *gorm.DB) error {
 	if t.ID == uuid.Nil {
 		t.ID = uuid.New()
 	}
+	if t.Version == 0 {
+		t.Version = 1
+	}
 	return nil
 }
 
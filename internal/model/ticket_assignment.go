@@ -15,6 +15,18 @@ const (
 	DriverMarkStatusCompleted  DriverMarkStatus = "COMPLETED"
 )
 
+// CanTransitionTo проверяет допустимость перехода: NOT_STARTED -> IN_WORK -> COMPLETED
+func (s DriverMarkStatus) CanTransitionTo(next DriverMarkStatus) bool {
+	switch s {
+	case DriverMarkStatusNotStarted:
+		return next == DriverMarkStatusInWork
+	case DriverMarkStatusInWork:
+		return next == DriverMarkStatusCompleted
+	default:
+		return false
+	}
+}
+
 type TicketAssignment struct {
 	ID               uuid.UUID         `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
 	TicketID         uuid.UUID         `gorm:"type:uuid;not null;index" json:"ticket_id"`
@@ -23,9 +35,21 @@ type TicketAssignment struct {
 	DriverMarkStatus DriverMarkStatus  `gorm:"type:driver_mark_status;not null;default:NOT_STARTED" json:"driver_mark_status"`
 	AssignedAt       time.Time         `gorm:"not null;default:now()" json:"assigned_at"`
 	UnassignedAt     *time.Time        `json:"unassigned_at"`
+	UnassignedBy     *uuid.UUID        `gorm:"type:uuid" json:"unassigned_by,omitempty"`
+	UnassignReason   *string           `gorm:"type:text" json:"unassign_reason,omitempty"`
 	IsActive         bool              `gorm:"not null;default:true" json:"is_active"`
+	StartedAt        *time.Time        `json:"started_at"`
+	CompletedAt      *time.Time        `json:"completed_at"`
+	StartedLat       *float64          `json:"started_lat,omitempty"`
+	StartedLon       *float64          `json:"started_lon,omitempty"`
+	CompletedLat     *float64          `json:"completed_lat,omitempty"`
+	CompletedLon     *float64          `json:"completed_lon,omitempty"`
+	OverlapReason    *string           `gorm:"type:text" json:"overlap_reason,omitempty"` // обоснование назначения поверх пересекающихся работ
+	ReplacesID       *uuid.UUID        `gorm:"type:uuid" json:"replaces_id,omitempty"`    // назначение, замененное этим при смене техники/водителя
+	Version          int64             `gorm:"not null;default:1" json:"version"`
 	CreatedAt        time.Time         `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt        time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+	Warnings         []string          `gorm:"-" json:"warnings,omitempty"` // предупреждения при создании назначения
 }
 
 func (TicketAssignment) TableName() string {
@@ -39,6 +63,9 @@ func (ta *TicketAssignment) BeforeCreate(tx *gorm.DB) error {
 	if ta.AssignedAt.IsZero() {
 		ta.AssignedAt = time.Now()
 	}
+	if ta.Version == 0 {
+		ta.Version = 1
+	}
 	return nil
 }
 
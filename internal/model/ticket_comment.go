@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TicketComment - сообщение в обсуждении тикета между КГУ, подрядчиком и Акиматом
+type TicketComment struct {
+	ID              uuid.UUID              `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	TicketID        uuid.UUID              `gorm:"type:uuid;not null;index" json:"ticket_id"`
+	CreatedByUserID uuid.UUID              `gorm:"type:uuid;not null" json:"created_by_user_id"`
+	AuthorRole      UserRole               `gorm:"type:varchar(32);not null" json:"author_role"`
+	Content         string                 `gorm:"type:text;not null" json:"content"`
+	Mentions        []TicketCommentMention `gorm:"foreignKey:CommentID" json:"mentions"`
+	EditedAt        *time.Time             `json:"edited_at"`
+	DeletedAt       *time.Time             `json:"deleted_at"`
+	CreatedAt       time.Time              `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time              `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (TicketComment) TableName() string {
+	return "ticket_comments"
+}
+
+func (tc *TicketComment) BeforeCreate(tx *gorm.DB) error {
+	if tc.ID == uuid.Nil {
+		tc.ID = uuid.New()
+	}
+	return nil
+}
+
+// TicketCommentMention - упоминание пользователя в комментарии
+type TicketCommentMention struct {
+	CommentID uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+}
+
+func (TicketCommentMention) TableName() string {
+	return "ticket_comment_mentions"
+}
+
+// TicketCommentRead - отметка о прочтении обсуждения тикета пользователем
+type TicketCommentRead struct {
+	TicketID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"ticket_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	LastReadAt time.Time `gorm:"not null" json:"last_read_at"`
+}
+
+func (TicketCommentRead) TableName() string {
+	return "ticket_comment_reads"
+}
@@ -17,6 +17,16 @@ const (
 	TripStatusSuspiciousVolume  TripStatus = "SUSPICIOUS_VOLUME"
 )
 
+// IsValid проверяет, что статус рейса входит в допустимый набор значений
+func (s TripStatus) IsValid() bool {
+	switch s {
+	case TripStatusOK, TripStatusRouteViolation, TripStatusMismatchPlate,
+		TripStatusNoAssignment, TripStatusSuspiciousVolume:
+		return true
+	}
+	return false
+}
+
 type Trip struct {
 	ID                  uuid.UUID    `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
 	TicketID            *uuid.UUID   `gorm:"type:uuid;index" json:"ticket_id"`
@@ -51,3 +61,27 @@ func (t *Trip) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// TripStatusChange - запись истории изменения статуса рейса (например, по итогам обжалования).
+// Исходное нарушение сохраняется в FromStatus
+type TripStatusChange struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	TripID           uuid.UUID  `gorm:"type:uuid;not null;index" json:"trip_id"`
+	FromStatus       TripStatus `gorm:"type:trip_status;not null" json:"from_status"`
+	ToStatus         TripStatus `gorm:"type:trip_status;not null" json:"to_status"`
+	FromAssignmentID *uuid.UUID `gorm:"type:uuid" json:"from_assignment_id"`
+	ToAssignmentID   *uuid.UUID `gorm:"type:uuid" json:"to_assignment_id"`
+	AppealID         *uuid.UUID `gorm:"type:uuid" json:"appeal_id"`
+	ChangedByUserID  uuid.UUID  `gorm:"type:uuid;not null" json:"changed_by_user_id"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (TripStatusChange) TableName() string {
+	return "trip_status_history"
+}
+
+func (c *TripStatusChange) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket-service/internal/model"
+)
+
+type AppealReasonRepository struct {
+	db *gorm.DB
+}
+
+func NewAppealReasonRepository(db *gorm.DB) *AppealReasonRepository {
+	return &AppealReasonRepository{db: db}
+}
+
+// Create сохраняет причину вместе со статусами рейса, которые она позволяет обжаловать
+func (r *AppealReasonRepository) Create(ctx context.Context, reason *model.AppealReasonType) error {
+	return r.db.WithContext(ctx).Create(reason).Error
+}
+
+func (r *AppealReasonRepository) GetByCode(ctx context.Context, code string) (*model.AppealReasonType, error) {
+	var reason model.AppealReasonType
+	err := r.db.WithContext(ctx).Preload("TripStatuses").Where("code = ?", code).First(&reason).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &reason, nil
+}
+
+// List возвращает справочник причин. activeOnly - только действующие,
+// tripStatus - только причины, по которым можно обжаловать рейс с этим статусом
+func (r *AppealReasonRepository) List(ctx context.Context, activeOnly bool, tripStatus *model.TripStatus) ([]model.AppealReasonType, error) {
+	var reasons []model.AppealReasonType
+	query := r.db.WithContext(ctx).Preload("TripStatuses")
+
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	if tripStatus != nil {
+		query = query.Where("code IN (?)", r.db.Model(&model.AppealReasonTripStatus{}).
+			Select("reason_code").Where("trip_status = ?", *tripStatus))
+	}
+
+	err := query.Order("code ASC").Find(&reasons).Error
+	return reasons, err
+}
+
+// Update сохраняет причину, только если ее версия не изменилась с момента чтения,
+// и заменяет набор статусов рейса
+func (r *AppealReasonRepository) Update(ctx context.Context, reason *model.AppealReasonType) error {
+	expected := reason.Version
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		reason.Version = expected + 1
+		result := tx.Model(reason).
+			Where("version = ?", expected).
+			Select("*").Omit("code", "created_at", clause.Associations).
+			Updates(reason)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		if err := tx.Where("reason_code = ?", reason.Code).Delete(&model.AppealReasonTripStatus{}).Error; err != nil {
+			return err
+		}
+		for i := range reason.TripStatuses {
+			reason.TripStatuses[i].ReasonCode = reason.Code
+		}
+		if len(reason.TripStatuses) == 0 {
+			return nil
+		}
+		return tx.Create(&reason.TripStatuses).Error
+	})
+	if err != nil {
+		reason.Version = expected
+	}
+	return err
+}
@@ -3,9 +3,11 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"ticket-service/internal/model"
 )
@@ -18,8 +20,17 @@ func NewAppealRepository(db *gorm.DB) *AppealRepository {
 	return &AppealRepository{db: db}
 }
 
+// Create сохраняет обжалование вместе с его вложениями
 func (r *AppealRepository) Create(ctx context.Context, appeal *model.Appeal) error {
-	return r.db.WithContext(ctx).Create(appeal).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(appeal).Error; err != nil {
+			return err
+		}
+		for i := range appeal.Attachments {
+			appeal.Attachments[i].AppealID = appeal.ID
+		}
+		return (&AppealRepository{db: tx}).AddAttachments(ctx, appeal.Attachments)
+	})
 }
 
 func (r *AppealRepository) GetByID(ctx context.Context, id string) (*model.Appeal, error) {
@@ -34,8 +45,126 @@ func (r *AppealRepository) GetByID(ctx context.Context, id string) (*model.Appea
 	return &appeal, nil
 }
 
+// Update сохраняет обжалование только если его версия не изменилась с момента чтения
 func (r *AppealRepository) Update(ctx context.Context, appeal *model.Appeal) error {
-	return r.db.WithContext(ctx).Save(appeal).Error
+	expected := appeal.Version
+	appeal.Version = expected + 1
+	result := r.db.WithContext(ctx).Model(appeal).
+		Where("version = ?", expected).
+		Select("*").Omit("id", "created_at").
+		Updates(appeal)
+	if result.Error != nil {
+		appeal.Version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		appeal.Version = expected
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// ListReviewOverdue возвращает обжалования с истекшим сроком рассмотрения, еще не переданные Акимату.
+// У обжалований, поданных до появления сроков, срок отсчитывается от подачи
+func (r *AppealRepository) ListReviewOverdue(ctx context.Context, now time.Time, reviewTimeout time.Duration) ([]model.Appeal, error) {
+	var appeals []model.Appeal
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND escalated_at IS NULL",
+			[]model.AppealStatus{model.AppealStatusSubmitted, model.AppealStatusUnderReview}).
+		Where("review_due_at < ? OR (review_due_at IS NULL AND created_at < ?)", now, now.Add(-reviewTimeout)).
+		Find(&appeals).Error
+	return appeals, err
+}
+
+// ListResponseOverdue возвращает обжалования, на запрос информации по которым водитель не ответил в срок.
+// Если срок ответа не заполнен, он отсчитывается от последнего изменения обжалования
+func (r *AppealRepository) ListResponseOverdue(ctx context.Context, now time.Time, responseTimeout time.Duration) ([]model.Appeal, error) {
+	var appeals []model.Appeal
+	err := r.db.WithContext(ctx).
+		Where("status = ?", model.AppealStatusNeedInfo).
+		Where("response_due_at < ? OR (response_due_at IS NULL AND updated_at < ?)", now, now.Add(-responseTimeout)).
+		Find(&appeals).Error
+	return appeals, err
+}
+
+type AppealListFilter struct {
+	Status         *model.AppealStatus
+	TicketID       *string
+	ContractorID   *string
+	CreatedByOrgID *string
+	ReasonType     *string
+	CreatedFrom    *string
+	CreatedTo      *string
+	Escalated      *bool
+	ReviewerID     *string
+}
+
+// List возвращает обжалования по фильтру. Фильтры по подрядчику и КГУ применяются через тикет обжалования
+func (r *AppealRepository) List(ctx context.Context, filter AppealListFilter) ([]model.Appeal, error) {
+	var appeals []model.Appeal
+	query := r.db.WithContext(ctx).Model(&model.Appeal{})
+
+	if filter.ContractorID != nil || filter.CreatedByOrgID != nil {
+		query = query.Joins("JOIN tickets ON tickets.id = appeals.ticket_id")
+	}
+	if filter.Status != nil {
+		query = query.Where("appeals.status = ?", *filter.Status)
+	}
+	if filter.TicketID != nil {
+		query = query.Where("appeals.ticket_id = ?", *filter.TicketID)
+	}
+	if filter.ContractorID != nil {
+		query = query.Where("tickets.contractor_id = ?", *filter.ContractorID)
+	}
+	if filter.CreatedByOrgID != nil {
+		query = query.Where("tickets.created_by_org_id = ?", *filter.CreatedByOrgID)
+	}
+	if filter.ReasonType != nil {
+		query = query.Where("appeals.appeal_reason_type = ?", *filter.ReasonType)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("appeals.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("appeals.created_at <= ?", *filter.CreatedTo)
+	}
+	if filter.ReviewerID != nil {
+		query = query.Where("appeals.reviewer_id = ?", *filter.ReviewerID)
+	}
+	if filter.Escalated != nil {
+		if *filter.Escalated {
+			query = query.Where("appeals.escalated_at IS NOT NULL")
+		} else {
+			query = query.Where("appeals.escalated_at IS NULL")
+		}
+	}
+
+	err := query.Select("appeals.*").Order("appeals.created_at DESC").Find(&appeals).Error
+	return appeals, err
+}
+
+// Resolve сохраняет решение по обжалованию и, если передан рейс, его исправленный статус
+// с записью в историю одной транзакцией. У рейса меняются только статус, назначение и водитель,
+// остальные поля (события выезда, объем) могли измениться с момента чтения
+func (r *AppealRepository) Resolve(ctx context.Context, appeal *model.Appeal, trip *model.Trip, change *model.TripStatusChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := (&AppealRepository{db: tx}).Update(ctx, appeal); err != nil {
+			return err
+		}
+		if trip == nil {
+			return nil
+		}
+		if err := tx.Model(&model.Trip{}).
+			Where("id = ?", trip.ID).
+			Updates(map[string]interface{}{
+				"status":               trip.Status,
+				"ticket_assignment_id": trip.TicketAssignmentID,
+				"driver_id":            trip.DriverID,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
 }
 
 func (r *AppealRepository) ListByTicketID(ctx context.Context, ticketID uuid.UUID) ([]model.Appeal, error) {
@@ -51,30 +180,234 @@ func (r *AppealRepository) ListByTripID(ctx context.Context, tripID uuid.UUID) (
 	var appeals []model.Appeal
 	err := r.db.WithContext(ctx).
 		Where("trip_id = ?", tripID).
-		Order("created_at DESC").
+		Order("created_at ASC").
 		Find(&appeals).Error
 	return appeals, err
 }
 
-func (r *AppealRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]model.Appeal, error) {
+// ListInbox возвращает страницу обжалований водителя - поданных им или от его имени (новые сверху) -
+// и их общее число
+func (r *AppealRepository) ListInbox(ctx context.Context, userID, driverID uuid.UUID, status *model.AppealStatus, limit, offset int) ([]model.Appeal, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Appeal{}).Where("(created_by_user_id = ? OR driver_id = ?)", userID, driverID)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var appeals []model.Appeal
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&appeals).Error
+	return appeals, total, err
+}
+
+// CountByStatus считает обжалования водителя по статусам
+func (r *AppealRepository) CountByStatus(ctx context.Context, userID, driverID uuid.UUID) (map[model.AppealStatus]int64, error) {
+	var rows []struct {
+		Status model.AppealStatus
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.Appeal{}).
+		Select("status, COUNT(*) AS count").
+		Where("created_by_user_id = ? OR driver_id = ?", userID, driverID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[model.AppealStatus]int64, len(rows))
+	for _, row := range rows {
+		result[row.Status] = row.Count
+	}
+	return result, nil
+}
+
+// GetLatestComments возвращает последний публичный комментарий по каждому обжалованию
+func (r *AppealRepository) GetLatestComments(ctx context.Context, appealIDs []uuid.UUID) (map[uuid.UUID]model.AppealComment, error) {
+	result := make(map[uuid.UUID]model.AppealComment, len(appealIDs))
+	if len(appealIDs) == 0 {
+		return result, nil
+	}
+
+	var comments []model.AppealComment
+	err := r.db.WithContext(ctx).
+		Where("(appeal_id, created_at) IN (?)", r.db.Model(&model.AppealComment{}).
+			Select("appeal_id, MAX(created_at)").
+			Where("appeal_id IN ? AND visibility = ? AND deleted_at IS NULL", appealIDs, model.AppealCommentPublic).
+			Group("appeal_id")).
+		Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comment := range comments {
+		result[comment.AppealID] = comment
+	}
+	return result, nil
+}
+
+// MarkRead сдвигает отметку прочтения переписки по обжалованию пользователем
+func (r *AppealRepository) MarkRead(ctx context.Context, appealID, userID uuid.UUID, readAt time.Time) error {
+	read := model.AppealRead{
+		AppealID:   appealID,
+		UserID:     userID,
+		LastReadAt: readAt,
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "appeal_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"last_read_at": gorm.Expr("GREATEST(appeal_reads.last_read_at, EXCLUDED.last_read_at)")}),
+	}).Create(&read).Error
+}
+
+// CountUnread считает непрочитанные пользователем чужие публичные комментарии по каждому обжалованию
+func (r *AppealRepository) CountUnread(ctx context.Context, userID uuid.UUID, appealIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	result := make(map[uuid.UUID]int64, len(appealIDs))
+	if len(appealIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		AppealID uuid.UUID
+		Unread   int64
+	}
+	err := r.db.WithContext(ctx).Table("appeal_comments c").
+		Select("c.appeal_id AS appeal_id, COUNT(*) AS unread").
+		Joins("LEFT JOIN appeal_reads r ON r.appeal_id = c.appeal_id AND r.user_id = ?", userID).
+		Where("c.appeal_id IN ? AND c.created_by_user_id != ?", appealIDs, userID).
+		Where("c.visibility = ? AND c.deleted_at IS NULL", model.AppealCommentPublic).
+		Where("r.last_read_at IS NULL OR c.created_at > r.last_read_at").
+		Group("c.appeal_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.AppealID] = row.Unread
+	}
+	return result, nil
+}
+
+// ListByUserID возвращает обжалования, поданные пользователем или от имени водителя
+func (r *AppealRepository) ListByUserID(ctx context.Context, userID, driverID uuid.UUID) ([]model.Appeal, error) {
 	var appeals []model.Appeal
 	err := r.db.WithContext(ctx).
-		Where("created_by_user_id = ?", userID).
+		Where("created_by_user_id = ? OR driver_id = ?", userID, driverID).
 		Order("created_at DESC").
 		Find(&appeals).Error
 	return appeals, err
 }
 
-func (r *AppealRepository) GetCommentsByAppealID(ctx context.Context, appealID uuid.UUID) ([]model.AppealComment, error) {
+// GetCommentsByAppealID возвращает переписку по обжалованию. Без includeInternal - только
+// публичные комментарии, с ним - также внутренние заметки и историю правок
+func (r *AppealRepository) GetCommentsByAppealID(ctx context.Context, appealID uuid.UUID, includeInternal bool) ([]model.AppealComment, error) {
 	var comments []model.AppealComment
+	query := r.db.WithContext(ctx).
+		Preload("Attachments").
+		Where("appeal_id = ?", appealID)
+	if includeInternal {
+		query = query.Preload("Edits", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		})
+	} else {
+		query = query.Where("visibility = ?", model.AppealCommentPublic)
+	}
+	err := query.Order("created_at ASC").Find(&comments).Error
+	return comments, err
+}
+
+func (r *AppealRepository) GetCommentByID(ctx context.Context, id string) (*model.AppealComment, error) {
+	var comment model.AppealComment
+	err := r.db.WithContext(ctx).Preload("Attachments").Where("id = ?", id).First(&comment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// UpdateComment меняет текст комментария и сохраняет прежний текст в аудите
+func (r *AppealRepository) UpdateComment(ctx context.Context, id uuid.UUID, content string, editedAt time.Time, edit *model.AppealCommentEdit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.AppealComment{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{
+				"content":   content,
+				"edited_at": editedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(edit).Error
+	})
+}
+
+// DeleteComment помечает комментарий удаленным и сохраняет его текст в аудите.
+// Сообщение остается в ленте как удаленное
+func (r *AppealRepository) DeleteComment(ctx context.Context, id uuid.UUID, deletedAt time.Time, edit *model.AppealCommentEdit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.AppealComment{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Update("deleted_at", deletedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(edit).Error
+	})
+}
+
+// AddAttachments сохраняет вложения обжалования
+func (r *AppealRepository) AddAttachments(ctx context.Context, attachments []model.AppealAttachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&attachments).Error
+}
+
+// ListAttachments возвращает вложения самого обжалования, без вложений комментариев
+func (r *AppealRepository) ListAttachments(ctx context.Context, appealID uuid.UUID) ([]model.AppealAttachment, error) {
+	var attachments []model.AppealAttachment
 	err := r.db.WithContext(ctx).
-		Where("appeal_id = ?", appealID).
+		Where("appeal_id = ? AND comment_id IS NULL", appealID).
 		Order("created_at ASC").
-		Find(&comments).Error
-	return comments, err
+		Find(&attachments).Error
+	return attachments, err
 }
 
-func (r *AppealRepository) AddComment(ctx context.Context, comment *model.AppealComment) error {
-	return r.db.WithContext(ctx).Create(comment).Error
+// CountAttachments возвращает общее число вложений обжалования, включая вложения комментариев
+func (r *AppealRepository) CountAttachments(ctx context.Context, appealID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.AppealAttachment{}).
+		Where("appeal_id = ?", appealID).
+		Count(&count).Error
+	return count, err
+}
+
+// AddComment одной транзакцией сохраняет комментарий вместе с вложениями, отмечает его прочитанным
+// автором и, если передано обжалование, сохраняет его (с проверкой версии)
+func (r *AppealRepository) AddComment(ctx context.Context, comment *model.AppealComment, appeal *model.Appeal) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := &AppealRepository{db: tx}
+		if appeal != nil {
+			if err := txRepo.Update(ctx, appeal); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return txRepo.MarkRead(ctx, comment.AppealID, comment.CreatedByUserID, comment.CreatedAt)
+	})
 }
 
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket-service/internal/model"
+)
+
+type AppealReviewerRepository struct {
+	db *gorm.DB
+}
+
+func NewAppealReviewerRepository(db *gorm.DB) *AppealReviewerRepository {
+	return &AppealReviewerRepository{db: db}
+}
+
+// Save добавляет инспектора в состав рассматривающих организации или меняет его активность
+func (r *AppealReviewerRepository) Save(ctx context.Context, reviewer *model.AppealReviewer) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "org_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"is_active", "updated_at"}),
+	}).Create(reviewer).Error
+}
+
+func (r *AppealReviewerRepository) Get(ctx context.Context, orgID, userID uuid.UUID) (*model.AppealReviewer, error) {
+	var reviewer model.AppealReviewer
+	err := r.db.WithContext(ctx).Where("org_id = ? AND user_id = ?", orgID, userID).First(&reviewer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &reviewer, nil
+}
+
+func (r *AppealReviewerRepository) List(ctx context.Context, orgID uuid.UUID) ([]model.AppealReviewer, error) {
+	var reviewers []model.AppealReviewer
+	err := r.db.WithContext(ctx).
+		Where("org_id = ?", orgID).
+		Order("created_at ASC").
+		Find(&reviewers).Error
+	return reviewers, err
+}
+
+// Assign назначает рассматривающего одной транзакцией: сохраняет обжалование (с проверкой версии),
+// отмечает время назначения у инспектора и пишет запись в историю. Если ReviewerID в записи не задан,
+// инспектор выбирается по очереди - активный, которому дольше всех ничего не назначали.
+// Если активных инспекторов нет, возвращается gorm.ErrRecordNotFound
+func (r *AppealReviewerRepository) Assign(ctx context.Context, orgID uuid.UUID, appeal *model.Appeal, entry *model.AppealReviewerAssignment, at time.Time) error {
+	expected := appeal.Version
+	previous := appeal.ReviewerID
+	previousAt := appeal.ReviewerAssignedAt
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		reviewer, err := takeReviewer(tx, orgID, entry, at)
+		if err != nil {
+			return err
+		}
+
+		entry.ReviewerID = reviewer.UserID
+		appeal.ReviewerID = &reviewer.UserID
+		appeal.ReviewerAssignedAt = &at
+		if err := (&AppealRepository{db: tx}).Update(ctx, appeal); err != nil {
+			return err
+		}
+
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		appeal.Version = expected
+		appeal.ReviewerID = previous
+		appeal.ReviewerAssignedAt = previousAt
+	}
+	return err
+}
+
+// CreateAppeal сохраняет новое обжалование и в той же транзакции назначает ему инспектора
+// по очереди. Если активных инспекторов нет, обжалование сохраняется без рассматривающего
+func (r *AppealReviewerRepository) CreateAppeal(ctx context.Context, orgID uuid.UUID, appeal *model.Appeal, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		entry := &model.AppealReviewerAssignment{}
+		reviewer, err := takeReviewer(tx, orgID, entry, at)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if reviewer != nil {
+			appeal.ReviewerID = &reviewer.UserID
+			appeal.ReviewerAssignedAt = &at
+		}
+
+		if err := (&AppealRepository{db: tx}).Create(ctx, appeal); err != nil {
+			return err
+		}
+
+		if reviewer == nil {
+			return nil
+		}
+		entry.AppealID = appeal.ID
+		entry.ReviewerID = reviewer.UserID
+		return tx.Create(entry).Error
+	})
+}
+
+// takeReviewer выбирает инспектора для назначения, блокируя его строку до конца транзакции,
+// и отмечает время назначения. Если ReviewerID в записи не задан - следующий по очереди
+func takeReviewer(tx *gorm.DB, orgID uuid.UUID, entry *model.AppealReviewerAssignment, at time.Time) (*model.AppealReviewer, error) {
+	var reviewer model.AppealReviewer
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("org_id = ? AND is_active = ?", orgID, true)
+	if entry.ReviewerID != uuid.Nil {
+		query = query.Where("user_id = ?", entry.ReviewerID)
+	} else {
+		// При переназначении по очереди текущий инспектор не выбирается
+		if entry.PreviousReviewerID != nil {
+			query = query.Where("user_id != ?", *entry.PreviousReviewerID)
+		}
+		query = query.Order("last_assigned_at ASC NULLS FIRST").Order("created_at ASC")
+	}
+	if err := query.First(&reviewer).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&reviewer).
+		Where("org_id = ? AND user_id = ?", reviewer.OrgID, reviewer.UserID).
+		Update("last_assigned_at", at).Error; err != nil {
+		return nil, err
+	}
+	return &reviewer, nil
+}
+
+func (r *AppealReviewerRepository) ListAssignments(ctx context.Context, appealID uuid.UUID) ([]model.AppealReviewerAssignment, error) {
+	var assignments []model.AppealReviewerAssignment
+	err := r.db.WithContext(ctx).
+		Where("appeal_id = ?", appealID).
+		Order("created_at ASC").
+		Find(&assignments).Error
+	return assignments, err
+}
+
+// ListQueue возвращает открытые обжалования инспектора: ближайший срок - первым.
+// Для NEED_INFO срок - ответ водителя, для остальных - срок рассмотрения
+func (r *AppealReviewerRepository) ListQueue(ctx context.Context, reviewerID uuid.UUID) ([]model.Appeal, error) {
+	var appeals []model.Appeal
+	err := r.db.WithContext(ctx).
+		Where("reviewer_id = ? AND escalated_at IS NULL AND status IN ?", reviewerID, []model.AppealStatus{
+			model.AppealStatusSubmitted, model.AppealStatusUnderReview, model.AppealStatusNeedInfo,
+		}).
+		Order(clause.Expr{SQL: "CASE WHEN status = ? THEN response_due_at ELSE review_due_at END ASC NULLS LAST", Vars: []interface{}{model.AppealStatusNeedInfo}}).
+		Order("created_at ASC").
+		Find(&appeals).Error
+	return appeals, err
+}
+
+// ReviewerWorkload - нагрузка инспектора по обжалованиям
+type ReviewerWorkload struct {
+	ReviewerID uuid.UUID `json:"reviewer_id"`
+	Open       int64     `json:"open"`
+	NeedInfo   int64     `json:"need_info"`
+	Overdue    int64     `json:"overdue"`
+	Resolved   int64     `json:"resolved"`
+}
+
+// GetWorkload считает нагрузку инспекторов по обжалованиям тикетов организации.
+// Resolved - число решенных с момента since, Overdue - открытые с истекшим сроком рассмотрения
+func (r *AppealReviewerRepository) GetWorkload(ctx context.Context, orgID uuid.UUID, since, now time.Time) (map[uuid.UUID]ReviewerWorkload, error) {
+	var rows []ReviewerWorkload
+	err := r.db.WithContext(ctx).Model(&model.Appeal{}).
+		Select(`appeals.reviewer_id AS reviewer_id,
+			COUNT(*) FILTER (WHERE appeals.status IN ('SUBMITTED', 'UNDER_REVIEW', 'NEED_INFO') AND appeals.escalated_at IS NULL) AS open,
+			COUNT(*) FILTER (WHERE appeals.status = 'NEED_INFO' AND appeals.escalated_at IS NULL) AS need_info,
+			COUNT(*) FILTER (WHERE appeals.status IN ('SUBMITTED', 'UNDER_REVIEW') AND appeals.escalated_at IS NULL AND appeals.review_due_at < ?) AS overdue,
+			COUNT(*) FILTER (WHERE appeals.resolved_at >= ?) AS resolved`, now, since).
+		Joins("JOIN tickets ON tickets.id = appeals.ticket_id").
+		Where("tickets.created_by_org_id = ? AND appeals.reviewer_id IS NOT NULL", orgID).
+		Group("appeals.reviewer_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]ReviewerWorkload, len(rows))
+	for _, row := range rows {
+		result[row.ReviewerID] = row
+	}
+	return result, nil
+}
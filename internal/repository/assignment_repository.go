@@ -23,6 +23,28 @@ func (r *AssignmentRepository) Create(ctx context.Context, assignment *model.Tic
 	return r.db.WithContext(ctx).Create(assignment).Error
 }
 
+// CreateBatch создает назначения одной транзакцией
+func (r *AssignmentRepository) CreateBatch(ctx context.Context, assignments []*model.TicketAssignment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, assignment := range assignments {
+			if err := tx.Create(assignment).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetByIDs возвращает назначения по списку идентификаторов
+func (r *AssignmentRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]model.TicketAssignment, error) {
+	var assignments []model.TicketAssignment
+	if len(ids) == 0 {
+		return assignments, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&assignments).Error
+	return assignments, err
+}
+
 func (r *AssignmentRepository) GetByID(ctx context.Context, id string) (*model.TicketAssignment, error) {
 	var assignment model.TicketAssignment
 	err := r.db.WithContext(ctx).Where("id = ?", id).First(&assignment).Error
@@ -35,19 +57,61 @@ func (r *AssignmentRepository) GetByID(ctx context.Context, id string) (*model.T
 	return &assignment, nil
 }
 
+// Update сохраняет назначение только если его версия не изменилась с момента чтения
 func (r *AssignmentRepository) Update(ctx context.Context, assignment *model.TicketAssignment) error {
-	return r.db.WithContext(ctx).Save(assignment).Error
+	expected := assignment.Version
+	assignment.Version = expected + 1
+	result := r.db.WithContext(ctx).Model(assignment).
+		Where("version = ?", expected).
+		Select("*").Omit("id", "created_at").
+		Updates(assignment)
+	if result.Error != nil {
+		assignment.Version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		assignment.Version = expected
+		return ErrVersionConflict
+	}
+	return nil
 }
 
-func (r *AssignmentRepository) Delete(ctx context.Context, id string) error {
-	// Мягкое удаление - помечаем как неактивное
+func (r *AssignmentRepository) Delete(ctx context.Context, assignment *model.TicketAssignment) error {
+	// Мягкое удаление - помечаем как неактивное, запоминая кто и почему снял назначение
 	now := time.Now()
-	return r.db.WithContext(ctx).Model(&model.TicketAssignment{}).
-		Where("id = ?", id).
+	result := r.db.WithContext(ctx).Model(&model.TicketAssignment{}).
+		Where("id = ? AND version = ?", assignment.ID, assignment.Version).
 		Updates(map[string]interface{}{
-			"is_active":     false,
-			"unassigned_at": now,
-		}).Error
+			"is_active":       false,
+			"unassigned_at":   now,
+			"unassigned_by":   assignment.UnassignedBy,
+			"unassign_reason": assignment.UnassignReason,
+			"version":         gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	assignment.IsActive = false
+	assignment.UnassignedAt = &now
+	assignment.Version++
+	return nil
+}
+
+// DeleteBatch снимает назначения одной транзакцией. Если хотя бы одно назначение
+// изменилось с момента чтения, не снимается ни одно
+func (r *AssignmentRepository) DeleteBatch(ctx context.Context, assignments []*model.TicketAssignment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := &AssignmentRepository{db: tx}
+		for _, assignment := range assignments {
+			if err := txRepo.Delete(ctx, assignment); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (r *AssignmentRepository) ListByTicketID(ctx context.Context, ticketID uuid.UUID) ([]model.TicketAssignment, error) {
@@ -59,9 +123,249 @@ func (r *AssignmentRepository) ListByTicketID(ctx context.Context, ticketID uuid
 	return assignments, err
 }
 
-func (r *AssignmentRepository) UpdateDriverMarkStatus(ctx context.Context, id string, status model.DriverMarkStatus) error {
-	return r.db.WithContext(ctx).Model(&model.TicketAssignment{}).
-		Where("id = ?", id).
-		Update("driver_mark_status", status).Error
+// Swap закрывает назначение old в момент at и открывает replacement одной транзакцией.
+// На новое назначение переносятся рейсы old, начатые после at, только если камера зафиксировала
+// на них технику replacement; зафиксированная техника рейса не меняется. Рейсы тикета без назначения
+// на технике replacement привязываются к нему и получают статус OK с записью в истории рейса
+func (r *AssignmentRepository) Swap(ctx context.Context, old, replacement *model.TicketAssignment, at time.Time, changedBy uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.TicketAssignment{}).
+			Where("id = ? AND version = ? AND is_active = ?", old.ID, old.Version, true).
+			Updates(map[string]interface{}{
+				"is_active":       false,
+				"unassigned_at":   at,
+				"unassigned_by":   old.UnassignedBy,
+				"unassign_reason": old.UnassignReason,
+				"version":         gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Trip{}).
+			Where("ticket_assignment_id = ? AND entry_at >= ? AND vehicle_id = ?", old.ID, at, replacement.VehicleID).
+			Updates(map[string]interface{}{
+				"ticket_assignment_id": replacement.ID,
+				"driver_id":            replacement.DriverID,
+			}).Error; err != nil {
+			return err
+		}
+
+		var unassigned []model.Trip
+		if err := tx.
+			Where("ticket_id = ? AND ticket_assignment_id IS NULL AND vehicle_id = ? AND entry_at >= ? AND status = ?",
+				replacement.TicketID, replacement.VehicleID, at, model.TripStatusNoAssignment).
+			Find(&unassigned).Error; err != nil {
+			return err
+		}
+		if len(unassigned) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(unassigned))
+		changes := make([]model.TripStatusChange, 0, len(unassigned))
+		for _, trip := range unassigned {
+			ids = append(ids, trip.ID)
+			changes = append(changes, model.TripStatusChange{
+				TripID:          trip.ID,
+				FromStatus:      trip.Status,
+				ToStatus:        model.TripStatusOK,
+				ToAssignmentID:  &replacement.ID,
+				ChangedByUserID: changedBy,
+			})
+		}
+
+		if err := tx.Model(&model.Trip{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"ticket_assignment_id": replacement.ID,
+				"driver_id":            replacement.DriverID,
+				"status":               model.TripStatusOK,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&changes).Error
+	})
+}
+
+// GetReplacement возвращает назначение, заменившее указанное, или nil
+func (r *AssignmentRepository) GetReplacement(ctx context.Context, id uuid.UUID) (*model.TicketAssignment, error) {
+	var assignment model.TicketAssignment
+	err := r.db.WithContext(ctx).Where("replaces_id = ?", id).First(&assignment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// ListCurrentByDriverID возвращает активные незавершенные назначения водителя
+// на запланированных тикетах и тикетах в работе
+func (r *AssignmentRepository) ListCurrentByDriverID(ctx context.Context, driverID uuid.UUID) ([]model.TicketAssignment, error) {
+	var assignments []model.TicketAssignment
+	err := r.db.WithContext(ctx).Model(&model.TicketAssignment{}).
+		Joins("JOIN tickets ON tickets.id = ticket_assignments.ticket_id").
+		Where("ticket_assignments.driver_id = ? AND ticket_assignments.is_active = ?", driverID, true).
+		Where("ticket_assignments.driver_mark_status <> ?", model.DriverMarkStatusCompleted).
+		Where("tickets.status IN ?", []model.TicketStatus{model.TicketStatusPlanned, model.TicketStatusInProgress}).
+		Select("ticket_assignments.*").
+		Order("tickets.planned_start_at ASC").
+		Find(&assignments).Error
+	return assignments, err
+}
+
+// ListContractorCrew возвращает водителей и технику, назначавшихся на тикеты подрядчика начиная с since
+func (r *AssignmentRepository) ListContractorCrew(ctx context.Context, contractorID uuid.UUID, since time.Time) ([]uuid.UUID, []uuid.UUID, error) {
+	base := func() *gorm.DB {
+		return r.db.WithContext(ctx).Model(&model.TicketAssignment{}).
+			Joins("JOIN tickets ON tickets.id = ticket_assignments.ticket_id").
+			Where("tickets.contractor_id = ? AND ticket_assignments.assigned_at >= ?", contractorID, since)
+	}
+
+	var driverIDs, vehicleIDs []uuid.UUID
+	if err := base().Distinct().Pluck("ticket_assignments.driver_id", &driverIDs).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := base().Distinct().Pluck("ticket_assignments.vehicle_id", &vehicleIDs).Error; err != nil {
+		return nil, nil, err
+	}
+	return driverIDs, vehicleIDs, nil
+}
+
+// IsContractorDriver сообщает, что водитель работает у подрядчика: у него есть активное назначение
+// на тикет подрядчика или назначение, выданное начиная с since
+func (r *AssignmentRepository) IsContractorDriver(ctx context.Context, contractorID, driverID uuid.UUID, since time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.TicketAssignment{}).
+		Joins("JOIN tickets ON tickets.id = ticket_assignments.ticket_id").
+		Where("tickets.contractor_id = ? AND ticket_assignments.driver_id = ?", contractorID, driverID).
+		Where("ticket_assignments.is_active = ? OR ticket_assignments.assigned_at >= ?", true, since).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListHistoryByTicketID возвращает все назначения тикета, включая снятые
+func (r *AssignmentRepository) ListHistoryByTicketID(ctx context.Context, ticketID uuid.UUID) ([]model.TicketAssignment, error) {
+	var assignments []model.TicketAssignment
+	err := r.db.WithContext(ctx).
+		Where("ticket_id = ?", ticketID).
+		Order("assigned_at ASC").
+		Find(&assignments).Error
+	return assignments, err
+}
+
+// ListMarkedBetween возвращает назначения водителей (в том числе снятые) с отметкой начала работ,
+// период работы по которым пересекается с [from, to). Если задан contractorID - только по его тикетам
+func (r *AssignmentRepository) ListMarkedBetween(ctx context.Context, driverIDs []uuid.UUID, contractorID *uuid.UUID, from, to time.Time) ([]model.TicketAssignment, error) {
+	var assignments []model.TicketAssignment
+	query := r.db.WithContext(ctx).Model(&model.TicketAssignment{}).
+		Where("ticket_assignments.started_at IS NOT NULL AND ticket_assignments.started_at < ?", to).
+		Where("ticket_assignments.completed_at IS NULL OR ticket_assignments.completed_at > ?", from)
+
+	if len(driverIDs) > 0 {
+		query = query.Where("ticket_assignments.driver_id IN ?", driverIDs)
+	}
+	if contractorID != nil {
+		query = query.Joins("JOIN tickets ON tickets.id = ticket_assignments.ticket_id").
+			Where("tickets.contractor_id = ?", *contractorID)
+	}
+
+	err := query.Select("ticket_assignments.*").Find(&assignments).Error
+	return assignments, err
+}
+
+// Booking - активное назначение вместе с плановым окном его тикета
+type Booking struct {
+	AssignmentID   uuid.UUID
+	TicketID       uuid.UUID
+	DriverID       uuid.UUID
+	VehicleID      uuid.UUID
+	TicketStatus   model.TicketStatus
+	PlannedStartAt time.Time
+	PlannedEndAt   time.Time
+}
+
+// FindOverlappingBookings возвращает активные назначения указанных водителей или техники
+// на незавершенных тикетах, плановое окно которых пересекается с [start, end)
+func (r *AssignmentRepository) FindOverlappingBookings(ctx context.Context, driverIDs, vehicleIDs []uuid.UUID, start, end time.Time) ([]Booking, error) {
+	var bookings []Booking
+	if len(driverIDs) == 0 && len(vehicleIDs) == 0 {
+		return bookings, nil
+	}
+
+	query := r.db.WithContext(ctx).Table("ticket_assignments ta").
+		Select("ta.id AS assignment_id, ta.ticket_id, ta.driver_id, ta.vehicle_id, "+
+			"t.status AS ticket_status, t.planned_start_at, t.planned_end_at").
+		Joins("JOIN tickets t ON t.id = ta.ticket_id").
+		Where("ta.is_active = ?", true).
+		Where("t.status IN ?", []model.TicketStatus{model.TicketStatusPlanned, model.TicketStatusInProgress}).
+		Where("t.planned_start_at < ? AND t.planned_end_at > ?", end, start)
+
+	switch {
+	case len(driverIDs) > 0 && len(vehicleIDs) > 0:
+		query = query.Where("ta.driver_id IN ? OR ta.vehicle_id IN ?", driverIDs, vehicleIDs)
+	case len(driverIDs) > 0:
+		query = query.Where("ta.driver_id IN ?", driverIDs)
+	default:
+		query = query.Where("ta.vehicle_id IN ?", vehicleIDs)
+	}
+
+	err := query.Order("t.planned_start_at ASC").Scan(&bookings).Error
+	return bookings, err
+}
+
+// UpdateDriverMarkStatus сохраняет отметку водителя вместе с ее временем и координатами.
+// Если передан ticket, в той же транзакции сохраняются его статус и фактическое начало;
+// при конфликте версий не сохраняется ни отметка, ни тикет
+func (r *AssignmentRepository) UpdateDriverMarkStatus(ctx context.Context, assignment *model.TicketAssignment, ticket *model.Ticket) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.TicketAssignment{}).
+			Where("id = ? AND version = ? AND is_active = ?", assignment.ID, assignment.Version, true).
+			Updates(map[string]interface{}{
+				"driver_mark_status": assignment.DriverMarkStatus,
+				"started_at":         assignment.StartedAt,
+				"completed_at":       assignment.CompletedAt,
+				"started_lat":        assignment.StartedLat,
+				"started_lon":        assignment.StartedLon,
+				"completed_lat":      assignment.CompletedLat,
+				"completed_lon":      assignment.CompletedLon,
+				"version":            gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		if ticket == nil {
+			return nil
+		}
+		result = tx.Model(&model.Ticket{}).
+			Where("id = ? AND version = ?", ticket.ID, ticket.Version).
+			Updates(map[string]interface{}{
+				"status":        ticket.Status,
+				"fact_start_at": ticket.FactStartAt,
+				"version":       gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return nil
+	})
 }
 
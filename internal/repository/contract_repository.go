@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket-service/internal/model"
+)
+
+type ContractRepository struct {
+	db *gorm.DB
+}
+
+func NewContractRepository(db *gorm.DB) *ContractRepository {
+	return &ContractRepository{db: db}
+}
+
+func (r *ContractRepository) Create(ctx context.Context, contract *model.Contract) error {
+	return r.db.WithContext(ctx).Create(contract).Error
+}
+
+func (r *ContractRepository) GetByID(ctx context.Context, id string) (*model.Contract, error) {
+	var contract model.Contract
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&contract).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &contract, nil
+}
+
+// GetByIDForUpdate читает договор и блокирует его строку до конца транзакции,
+// чтобы проверка лимита и создание тикета не разъехались с параллельными запросами
+func (r *ContractRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.Contract, error) {
+	var contract model.Contract
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).First(&contract).Error
+	if err != nil {
+		return nil, err
+	}
+	return &contract, nil
+}
+
+type ContractListFilter struct {
+	ContractorID   *string
+	CreatedByOrgID *string
+	ActiveAt       *string
+}
+
+func (r *ContractRepository) List(ctx context.Context, filter ContractListFilter) ([]model.Contract, error) {
+	var contracts []model.Contract
+	query := r.db.WithContext(ctx).Model(&model.Contract{})
+
+	if filter.ContractorID != nil {
+		query = query.Where("contractor_id = ?", *filter.ContractorID)
+	}
+	if filter.CreatedByOrgID != nil {
+		query = query.Where("created_by_org_id = ?", *filter.CreatedByOrgID)
+	}
+	if filter.ActiveAt != nil {
+		query = query.Where("starts_at <= ? AND ends_at >= ?", *filter.ActiveAt, *filter.ActiveAt)
+	}
+
+	if err := query.Order("starts_at DESC").Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+
+	return contracts, nil
+}
+
+// GetUsedVolumes возвращает вывезенный объем (сумма detected_volume_entry рейсов,
+// как в TicketMetrics.TotalVolumeM3) по всем неотмененным тикетам каждого договора
+func (r *ContractRepository) GetUsedVolumes(ctx context.Context, contractIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	result := make(map[uuid.UUID]float64, len(contractIDs))
+	if len(contractIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		ContractID uuid.UUID
+		Volume     float64
+	}
+	err := r.db.WithContext(ctx).Model(&model.Trip{}).
+		Select("tickets.contract_id AS contract_id, COALESCE(SUM(trips.detected_volume_entry), 0) AS volume").
+		Joins("JOIN tickets ON tickets.id = trips.ticket_id").
+		Where("tickets.contract_id IN ? AND tickets.status != ?", contractIDs, model.TicketStatusCancelled).
+		Group("tickets.contract_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.ContractID] = row.Volume
+	}
+	return result, nil
+}
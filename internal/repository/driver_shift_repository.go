@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ticket-service/internal/model"
+)
+
+type DriverShiftRepository struct {
+	db *gorm.DB
+}
+
+func NewDriverShiftRepository(db *gorm.DB) *DriverShiftRepository {
+	return &DriverShiftRepository{db: db}
+}
+
+func (r *DriverShiftRepository) Create(ctx context.Context, shift *model.DriverShift) error {
+	return r.db.WithContext(ctx).Create(shift).Error
+}
+
+func (r *DriverShiftRepository) GetByID(ctx context.Context, id string) (*model.DriverShift, error) {
+	var shift model.DriverShift
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&shift).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// GetOpenByDriverID возвращает открытую смену водителя или nil
+func (r *DriverShiftRepository) GetOpenByDriverID(ctx context.Context, driverID uuid.UUID) (*model.DriverShift, error) {
+	var shift model.DriverShift
+	err := r.db.WithContext(ctx).
+		Where("driver_id = ? AND ended_at IS NULL", driverID).
+		First(&shift).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// Close закрывает смену, если она еще открыта
+func (r *DriverShiftRepository) Close(ctx context.Context, shift *model.DriverShift) error {
+	result := r.db.WithContext(ctx).Model(&model.DriverShift{}).
+		Where("id = ? AND ended_at IS NULL", shift.ID).
+		Updates(map[string]interface{}{
+			"ended_at":  shift.EndedAt,
+			"closed_by": shift.ClosedBy,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+type DriverShiftListFilter struct {
+	ContractorID *uuid.UUID
+	DriverIDs    []uuid.UUID
+	From         *time.Time
+	To           *time.Time
+}
+
+// List возвращает смены, пересекающиеся с периодом [From, To)
+func (r *DriverShiftRepository) List(ctx context.Context, filter DriverShiftListFilter) ([]model.DriverShift, error) {
+	var shifts []model.DriverShift
+	query := r.db.WithContext(ctx).Model(&model.DriverShift{})
+
+	if filter.ContractorID != nil {
+		query = query.Where("contractor_id = ?", *filter.ContractorID)
+	}
+	if len(filter.DriverIDs) > 0 {
+		query = query.Where("driver_id IN ?", filter.DriverIDs)
+	}
+	if filter.From != nil {
+		query = query.Where("ended_at IS NULL OR ended_at > ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("started_at < ?", *filter.To)
+	}
+
+	err := query.Order("started_at DESC").Find(&shifts).Error
+	return shifts, err
+}
@@ -0,0 +1,7 @@
+package repository
+
+import "errors"
+
+// ErrVersionConflict возвращается, когда запись была изменена другим запросом
+// после того, как ее прочитали (оптимистическая блокировка по колонке version)
+var ErrVersionConflict = errors.New("version conflict")
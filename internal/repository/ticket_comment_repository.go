@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket-service/internal/model"
+)
+
+type TicketCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewTicketCommentRepository(db *gorm.DB) *TicketCommentRepository {
+	return &TicketCommentRepository{db: db}
+}
+
+// Create сохраняет комментарий вместе с упоминаниями
+func (r *TicketCommentRepository) Create(ctx context.Context, comment *model.TicketComment) error {
+	return r.db.WithContext(ctx).Create(comment).Error
+}
+
+func (r *TicketCommentRepository) GetByID(ctx context.Context, id string) (*model.TicketComment, error) {
+	var comment model.TicketComment
+	err := r.db.WithContext(ctx).Preload("Mentions").Where("id = ?", id).First(&comment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *TicketCommentRepository) UpdateContent(ctx context.Context, id uuid.UUID, content string, editedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.TicketComment{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"content":   content,
+			"edited_at": editedAt,
+		}).Error
+}
+
+func (r *TicketCommentRepository) Delete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error {
+	// Мягкое удаление - сообщение остается в ленте как удаленное
+	return r.db.WithContext(ctx).Model(&model.TicketComment{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Update("deleted_at", deletedAt).Error
+}
+
+func (r *TicketCommentRepository) ListByTicketID(ctx context.Context, ticketID uuid.UUID) ([]model.TicketComment, error) {
+	var comments []model.TicketComment
+	err := r.db.WithContext(ctx).
+		Preload("Mentions").
+		Where("ticket_id = ?", ticketID).
+		Order("created_at ASC").
+		Find(&comments).Error
+	return comments, err
+}
+
+// MarkRead сдвигает отметку прочтения обсуждения тикета пользователем
+func (r *TicketCommentRepository) MarkRead(ctx context.Context, ticketID, userID uuid.UUID, readAt time.Time) error {
+	read := model.TicketCommentRead{
+		TicketID:   ticketID,
+		UserID:     userID,
+		LastReadAt: readAt,
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ticket_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"last_read_at": gorm.Expr("GREATEST(ticket_comment_reads.last_read_at, EXCLUDED.last_read_at)")}),
+	}).Create(&read).Error
+}
+
+func (r *TicketCommentRepository) ListReads(ctx context.Context, ticketID uuid.UUID) ([]model.TicketCommentRead, error) {
+	var reads []model.TicketCommentRead
+	err := r.db.WithContext(ctx).
+		Where("ticket_id = ?", ticketID).
+		Find(&reads).Error
+	return reads, err
+}
+
+// CountUnread считает непрочитанные пользователем чужие комментарии по каждому тикету
+func (r *TicketCommentRepository) CountUnread(ctx context.Context, userID uuid.UUID, ticketIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	result := make(map[uuid.UUID]int64, len(ticketIDs))
+	if len(ticketIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		TicketID uuid.UUID
+		Unread   int64
+	}
+	err := r.db.WithContext(ctx).Table("ticket_comments c").
+		Select("c.ticket_id AS ticket_id, COUNT(*) AS unread").
+		Joins("LEFT JOIN ticket_comment_reads r ON r.ticket_id = c.ticket_id AND r.user_id = ?", userID).
+		Where("c.ticket_id IN ? AND c.deleted_at IS NULL AND c.created_by_user_id != ?", ticketIDs, userID).
+		Where("r.last_read_at IS NULL OR c.created_at > r.last_read_at").
+		Group("c.ticket_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.TicketID] = row.Unread
+	}
+	return result, nil
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -22,6 +24,22 @@ func (r *TicketRepository) Create(ctx context.Context, ticket *model.Ticket) err
 	return r.db.WithContext(ctx).Create(ticket).Error
 }
 
+// CreateBatch создает несколько тикетов одним запросом (все или ни одного)
+func (r *TicketRepository) CreateBatch(ctx context.Context, tickets []*model.Ticket) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&tickets).Error
+}
+
+// Transaction выполняет fn в одной транзакции. Переданные репозитории работают поверх нее,
+// поэтому блокировки, взятые внутри fn, держатся до создания тикетов
+func (r *TicketRepository) Transaction(ctx context.Context, fn func(tickets *TicketRepository, contracts *ContractRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&TicketRepository{db: tx}, &ContractRepository{db: tx})
+	})
+}
+
 func (r *TicketRepository) GetByID(ctx context.Context, id string) (*model.Ticket, error) {
 	var ticket model.Ticket
 	err := r.db.WithContext(ctx).Where("id = ?", id).First(&ticket).Error
@@ -34,8 +52,69 @@ func (r *TicketRepository) GetByID(ctx context.Context, id string) (*model.Ticke
 	return &ticket, nil
 }
 
+// GetByIDs возвращает тикеты по списку идентификаторов
+func (r *TicketRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Ticket, error) {
+	var tickets []model.Ticket
+	if len(ids) == 0 {
+		return tickets, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&tickets).Error
+	return tickets, err
+}
+
+// Update сохраняет тикет только если его версия не изменилась с момента чтения
 func (r *TicketRepository) Update(ctx context.Context, ticket *model.Ticket) error {
-	return r.db.WithContext(ctx).Save(ticket).Error
+	expected := ticket.Version
+	ticket.Version = expected + 1
+	result := r.db.WithContext(ctx).Model(ticket).
+		Where("version = ?", expected).
+		Select("*").Omit("id", "created_at").
+		Updates(ticket)
+	if result.Error != nil {
+		ticket.Version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		ticket.Version = expected
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// FindOverlapping возвращает неотмененные тикеты участка, плановое окно которых пересекается с [start, end)
+func (r *TicketRepository) FindOverlapping(ctx context.Context, cleaningAreaID uuid.UUID, start, end time.Time) ([]model.Ticket, error) {
+	var tickets []model.Ticket
+	err := r.db.WithContext(ctx).
+		Where("cleaning_area_id = ? AND status != ?", cleaningAreaID, model.TicketStatusCancelled).
+		Where("planned_start_at < ? AND planned_end_at > ?", end, start).
+		Order("planned_start_at ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+// LockAreas берет транзакционные advisory-блокировки на участки уборки, чтобы параллельные
+// запросы не создали пересекающиеся тикеты между проверкой и вставкой. Участки блокируются
+// в одном порядке, иначе две массовые выдачи могут заблокировать друг друга
+func (r *TicketRepository) LockAreas(ctx context.Context, cleaningAreaIDs []uuid.UUID) error {
+	ids := append([]uuid.UUID(nil), cleaningAreaIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	for _, id := range ids {
+		if err := r.db.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", id.String()).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAcceptanceOverdue возвращает неподтвержденные подрядчиком тикеты с истекшим сроком,
+// которые еще не эскалированы
+func (r *TicketRepository) ListAcceptanceOverdue(ctx context.Context, now time.Time) ([]model.Ticket, error) {
+	var tickets []model.Ticket
+	err := r.db.WithContext(ctx).
+		Where("acceptance = ? AND accept_due_at < ? AND escalated_at IS NULL", model.TicketAcceptancePending, now).
+		Where("status NOT IN ?", []model.TicketStatus{model.TicketStatusCancelled, model.TicketStatusClosed}).
+		Find(&tickets).Error
+	return tickets, err
 }
 
 func (r *TicketRepository) CountTripsByTicketID(ctx context.Context, ticketID uuid.UUID) (int64, error) {
@@ -62,8 +141,20 @@ func (r *TicketRepository) CountIncompleteAssignmentsByTicketID(ctx context.Cont
 	return count, err
 }
 
+type TicketSort string
+
+const (
+	TicketSortCreatedAt      TicketSort = "created_at"
+	TicketSortPriority       TicketSort = "priority"
+	TicketSortPlannedStartAt TicketSort = "planned_start_at"
+	TicketSortSLADeadlineAt  TicketSort = "sla_deadline_at"
+)
+
 type TicketListFilter struct {
 	Status         *model.TicketStatus
+	Priority       *model.TicketPriority
+	Acceptance     *model.TicketAcceptance
+	Escalated      *bool
 	ContractorID   *string
 	CleaningAreaID *string
 	ContractID     *string
@@ -77,6 +168,7 @@ type TicketListFilter struct {
 	FactStartTo      *string
 	FactEndFrom      *string
 	FactEndTo        *string
+	SortBy           TicketSort
 }
 
 func (r *TicketRepository) List(ctx context.Context, filter TicketListFilter) ([]model.Ticket, error) {
@@ -86,6 +178,19 @@ func (r *TicketRepository) List(ctx context.Context, filter TicketListFilter) ([
 	if filter.Status != nil {
 		query = query.Where("status = ?", *filter.Status)
 	}
+	if filter.Priority != nil {
+		query = query.Where("tickets.priority = ?", *filter.Priority)
+	}
+	if filter.Acceptance != nil {
+		query = query.Where("tickets.acceptance = ?", *filter.Acceptance)
+	}
+	if filter.Escalated != nil {
+		if *filter.Escalated {
+			query = query.Where("tickets.escalated_at IS NOT NULL")
+		} else {
+			query = query.Where("tickets.escalated_at IS NULL")
+		}
+	}
 	if filter.ContractorID != nil {
 		query = query.Where("contractor_id = ?", *filter.ContractorID)
 	}
@@ -127,7 +232,21 @@ func (r *TicketRepository) List(ctx context.Context, filter TicketListFilter) ([
 		query = query.Where("fact_end_at <= ?", *filter.FactEndTo)
 	}
 
-	if err := query.Order("created_at DESC").Find(&tickets).Error; err != nil {
+	// Экстренные тикеты всегда выводятся первыми, чтобы их было видно в любом списке
+	query = query.Order("tickets.priority = 'EMERGENCY' DESC")
+	switch filter.SortBy {
+	case TicketSortPriority:
+		// ENUM ticket_priority упорядочен от EMERGENCY к NORMAL
+		query = query.Order("tickets.priority ASC").Order("tickets.planned_start_at ASC")
+	case TicketSortPlannedStartAt:
+		query = query.Order("tickets.planned_start_at ASC")
+	case TicketSortSLADeadlineAt:
+		query = query.Order("tickets.sla_deadline_at ASC NULLS LAST")
+	default:
+		query = query.Order("tickets.created_at DESC")
+	}
+
+	if err := query.Select("tickets.*").Find(&tickets).Error; err != nil {
 		return nil, err
 	}
 
@@ -204,3 +323,121 @@ func (r *TicketRepository) GetAppealsByTicketID(ctx context.Context, ticketID uu
 		Find(&appeals).Error
 	return appeals, err
 }
+
+// ContractorReportFilter - отбор данных для отчета по подрядчикам. Тикеты отбираются
+// по плановому началу, рейсы - по въезду, обжалования - по дате подачи в [From, To)
+type ContractorReportFilter struct {
+	From           time.Time
+	To             time.Time
+	ContractorID   *uuid.UUID
+	CreatedByOrgID *uuid.UUID
+	CleaningAreaID *uuid.UUID
+	ContractID     *uuid.UUID
+}
+
+// scopeTickets ограничивает таблицу tickets подрядчиком, КГУ, участком и договором
+func (f ContractorReportFilter) scopeTickets(query *gorm.DB) *gorm.DB {
+	if f.ContractorID != nil {
+		query = query.Where("tickets.contractor_id = ?", *f.ContractorID)
+	}
+	if f.CreatedByOrgID != nil {
+		query = query.Where("tickets.created_by_org_id = ?", *f.CreatedByOrgID)
+	}
+	if f.CleaningAreaID != nil {
+		query = query.Where("tickets.cleaning_area_id = ?", *f.CleaningAreaID)
+	}
+	if f.ContractID != nil {
+		query = query.Where("tickets.contract_id = ?", *f.ContractID)
+	}
+	return query
+}
+
+// ContractorTicketStatusCount - число тикетов подрядчика в статусе
+type ContractorTicketStatusCount struct {
+	ContractorID uuid.UUID
+	Status       model.TicketStatus
+	Count        int64
+}
+
+// CountTicketsByStatus считает тикеты подрядчиков по статусам
+func (r *TicketRepository) CountTicketsByStatus(ctx context.Context, filter ContractorReportFilter) ([]ContractorTicketStatusCount, error) {
+	var rows []ContractorTicketStatusCount
+	query := r.db.WithContext(ctx).Model(&model.Ticket{}).
+		Select("tickets.contractor_id AS contractor_id, tickets.status AS status, COUNT(*) AS count").
+		Where("tickets.planned_start_at >= ? AND tickets.planned_start_at < ?", filter.From, filter.To)
+	err := filter.scopeTickets(query).
+		Group("tickets.contractor_id, tickets.status").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ContractorCompletionStats - выполненные тикеты подрядчика: сколько закрыто в срок
+// (не позже SLA, а без него - планового окончания) и среднее время выполнения в часах
+type ContractorCompletionStats struct {
+	ContractorID       uuid.UUID
+	Completed          int64
+	CompletedOnTime    int64
+	AvgCompletionHours float64
+}
+
+// GetCompletionStats считает выполнение тикетов подрядчиков с заполненным фактическим окончанием
+func (r *TicketRepository) GetCompletionStats(ctx context.Context, filter ContractorReportFilter) ([]ContractorCompletionStats, error) {
+	var rows []ContractorCompletionStats
+	query := r.db.WithContext(ctx).Model(&model.Ticket{}).
+		Select("tickets.contractor_id AS contractor_id, COUNT(*) AS completed, "+
+			"COUNT(*) FILTER (WHERE tickets.fact_end_at <= COALESCE(tickets.sla_deadline_at, tickets.planned_end_at)) AS completed_on_time, "+
+			"COALESCE(AVG(EXTRACT(EPOCH FROM tickets.fact_end_at - COALESCE(tickets.fact_start_at, tickets.planned_start_at))) / 3600, 0) AS avg_completion_hours").
+		Where("tickets.planned_start_at >= ? AND tickets.planned_start_at < ?", filter.From, filter.To).
+		Where("tickets.fact_end_at IS NOT NULL AND tickets.status IN ?", []model.TicketStatus{model.TicketStatusCompleted, model.TicketStatusClosed})
+	err := filter.scopeTickets(query).
+		Group("tickets.contractor_id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ContractorTripStatusStats - рейсы подрядчика с одним статусом и вывезенный ими объем
+type ContractorTripStatusStats struct {
+	ContractorID uuid.UUID
+	Status       model.TripStatus
+	TripsCount   int64
+	Volume       float64
+}
+
+// GetTripStatsByStatus считает рейсы и объем по тикетам подрядчиков в разрезе статусов рейса
+func (r *TicketRepository) GetTripStatsByStatus(ctx context.Context, filter ContractorReportFilter) ([]ContractorTripStatusStats, error) {
+	var rows []ContractorTripStatusStats
+	query := r.db.WithContext(ctx).Model(&model.Trip{}).
+		Select("tickets.contractor_id AS contractor_id, trips.status AS status, COUNT(*) AS trips_count, "+
+			"COALESCE(SUM(trips.detected_volume_entry), 0) AS volume").
+		Joins("JOIN tickets ON tickets.id = trips.ticket_id").
+		Where("trips.entry_at >= ? AND trips.entry_at < ?", filter.From, filter.To)
+	err := filter.scopeTickets(query).
+		Group("tickets.contractor_id, trips.status").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ContractorAppealStats - обжалования по тикетам подрядчика и вынесенные по ним решения
+type ContractorAppealStats struct {
+	ContractorID uuid.UUID
+	Total        int64
+	Approved     int64
+	Rejected     int64
+}
+
+// GetAppealStats считает обжалования по тикетам подрядчиков. Обжалование, отказ по которому
+// обжалован в Акимате, не учитывается - в расчет идет итоговое решение Акимата
+func (r *TicketRepository) GetAppealStats(ctx context.Context, filter ContractorReportFilter) ([]ContractorAppealStats, error) {
+	var rows []ContractorAppealStats
+	query := r.db.WithContext(ctx).Model(&model.Appeal{}).
+		Select("tickets.contractor_id AS contractor_id, COUNT(*) AS total, "+
+			"COUNT(*) FILTER (WHERE appeals.decision = ?) AS approved, "+
+			"COUNT(*) FILTER (WHERE appeals.decision = ?) AS rejected", model.AppealStatusApproved, model.AppealStatusRejected).
+		Joins("JOIN tickets ON tickets.id = appeals.ticket_id").
+		Where("appeals.created_at >= ? AND appeals.created_at < ?", filter.From, filter.To).
+		Where("NOT EXISTS (SELECT 1 FROM appeals child WHERE child.parent_appeal_id = appeals.id)")
+	err := filter.scopeTickets(query).
+		Group("tickets.contractor_id").
+		Scan(&rows).Error
+	return rows, err
+}
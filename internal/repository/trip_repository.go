@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -38,6 +39,36 @@ func (r *TripRepository) Update(ctx context.Context, trip *model.Trip) error {
 	return r.db.WithContext(ctx).Save(trip).Error
 }
 
+// GetLprEvents возвращает события распознавания номеров по идентификаторам
+func (r *TripRepository) GetLprEvents(ctx context.Context, ids []uuid.UUID) ([]model.LprEvent, error) {
+	var events []model.LprEvent
+	if len(ids) == 0 {
+		return events, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&events).Error
+	return events, err
+}
+
+// GetVolumeEvents возвращает события определения объема по идентификаторам
+func (r *TripRepository) GetVolumeEvents(ctx context.Context, ids []uuid.UUID) ([]model.VolumeEvent, error) {
+	var events []model.VolumeEvent
+	if len(ids) == 0 {
+		return events, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&events).Error
+	return events, err
+}
+
+// ListStatusHistory возвращает историю изменения статуса рейса
+func (r *TripRepository) ListStatusHistory(ctx context.Context, tripID uuid.UUID) ([]model.TripStatusChange, error) {
+	var history []model.TripStatusChange
+	err := r.db.WithContext(ctx).
+		Where("trip_id = ?", tripID).
+		Order("created_at ASC").
+		Find(&history).Error
+	return history, err
+}
+
 func (r *TripRepository) ListByTicketID(ctx context.Context, ticketID uuid.UUID) ([]model.Trip, error) {
 	var trips []model.Trip
 	err := r.db.WithContext(ctx).
@@ -47,6 +78,104 @@ func (r *TripRepository) ListByTicketID(ctx context.Context, ticketID uuid.UUID)
 	return trips, err
 }
 
+// ListByAssignmentIDs возвращает рейсы, привязанные к указанным назначениям
+func (r *TripRepository) ListByAssignmentIDs(ctx context.Context, assignmentIDs []uuid.UUID) ([]model.Trip, error) {
+	var trips []model.Trip
+	if len(assignmentIDs) == 0 {
+		return trips, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("ticket_assignment_id IN ?", assignmentIDs).
+		Order("entry_at ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+// ListByDriverIDsBetween возвращает рейсы водителей с въездом в период [from, to)
+func (r *TripRepository) ListByDriverIDsBetween(ctx context.Context, driverIDs []uuid.UUID, from, to time.Time) ([]model.Trip, error) {
+	var trips []model.Trip
+	if len(driverIDs) == 0 {
+		return trips, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("driver_id IN ? AND entry_at < ? AND (exit_at IS NULL OR exit_at > ?)", driverIDs, to, from).
+		Order("entry_at ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+// AssignmentTripStats - количество рейсов и объем по назначению
+type AssignmentTripStats struct {
+	TicketAssignmentID uuid.UUID
+	TripsCount         int64
+	Volume             float64
+}
+
+// GetStatsByAssignmentIDs считает рейсы и объем по назначениям с въездом не раньше since
+func (r *TripRepository) GetStatsByAssignmentIDs(ctx context.Context, assignmentIDs []uuid.UUID, since time.Time) (map[uuid.UUID]AssignmentTripStats, error) {
+	result := make(map[uuid.UUID]AssignmentTripStats)
+	if len(assignmentIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []AssignmentTripStats
+	err := r.db.WithContext(ctx).Model(&model.Trip{}).
+		Select("ticket_assignment_id, COUNT(*) AS trips_count, COALESCE(SUM(detected_volume_entry), 0) AS volume").
+		Where("ticket_assignment_id IN ? AND entry_at >= ?", assignmentIDs, since).
+		Group("ticket_assignment_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.TicketAssignmentID] = row
+	}
+	return result, nil
+}
+
+// TripStats - рейсы водителя или техники: общее число, нарушения и средний объем
+type TripStats struct {
+	ID         uuid.UUID
+	TripsCount int64
+	Violations int64
+	AvgVolume  float64
+}
+
+// GetDriverStats считает рейсы водителей с въездом не раньше since
+func (r *TripRepository) GetDriverStats(ctx context.Context, driverIDs []uuid.UUID, since time.Time) (map[uuid.UUID]TripStats, error) {
+	return r.getStats(ctx, "driver_id", driverIDs, since)
+}
+
+// GetVehicleStats считает рейсы техники с въездом не раньше since
+func (r *TripRepository) GetVehicleStats(ctx context.Context, vehicleIDs []uuid.UUID, since time.Time) (map[uuid.UUID]TripStats, error) {
+	return r.getStats(ctx, "vehicle_id", vehicleIDs, since)
+}
+
+func (r *TripRepository) getStats(ctx context.Context, column string, ids []uuid.UUID, since time.Time) (map[uuid.UUID]TripStats, error) {
+	result := make(map[uuid.UUID]TripStats)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var rows []TripStats
+	err := r.db.WithContext(ctx).Model(&model.Trip{}).
+		Select(column+" AS id, COUNT(*) AS trips_count, "+
+			"COUNT(*) FILTER (WHERE status <> ?) AS violations, "+
+			"COALESCE(AVG(detected_volume_entry), 0) AS avg_volume", model.TripStatusOK).
+		Where(column+" IN ? AND entry_at >= ?", ids, since).
+		Group(column).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.ID] = row
+	}
+	return result, nil
+}
+
 func (r *TripRepository) ListByDriverID(ctx context.Context, driverID uuid.UUID, ticketID *uuid.UUID) ([]model.Trip, error) {
 	var trips []model.Trip
 	query := r.db.WithContext(ctx).Where("driver_id = ?", driverID)
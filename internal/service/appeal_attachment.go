@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"ticket-service/internal/model"
+)
+
+// AttachmentInput - файл, загруженный клиентом во внешнее хранилище
+type AttachmentInput struct {
+	URL         string
+	FileName    string
+	ContentType string
+	SizeBytes   int64
+}
+
+// TripEvidence - фото камер распознавания номеров и определения объема по рейсу
+type TripEvidence struct {
+	EntryLpr    *model.LprEvent    `json:"entry_lpr"`
+	ExitLpr     *model.LprEvent    `json:"exit_lpr"`
+	EntryVolume *model.VolumeEvent `json:"entry_volume"`
+	ExitVolume  *model.VolumeEvent `json:"exit_volume"`
+}
+
+// AddAttachments прикладывает файлы к обжалованию. Водитель и подрядчик прикладывают к своему
+// обжалованию, КГУ и Акимат - к рассматриваемому. К завершенному обжалованию приложить нельзя
+func (s *AppealService) AddAttachments(ctx context.Context, principal model.Principal, appealID string, inputs []AttachmentInput) ([]model.AppealAttachment, error) {
+	if !principal.IsDriver() && !principal.IsContractor() && !principal.IsToo() && !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: attachments are required", ErrInvalidInput)
+	}
+
+	appeal, err := s.GetByID(ctx, principal, appealID)
+	if err != nil {
+		return nil, err
+	}
+
+	if appeal.Status.IsResolution() {
+		return nil, fmt.Errorf("%w: appeal is already resolved", ErrConflict)
+	}
+
+	attachments, err := s.prepareAttachments(ctx, principal, appeal.ID, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.appealRepo.AddAttachments(ctx, attachments); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// prepareAttachments проверяет тип, размер и ссылку каждого файла и общий лимит вложений обжалования
+func (s *AppealService) prepareAttachments(ctx context.Context, principal model.Principal, appealID uuid.UUID, inputs []AttachmentInput) ([]model.AppealAttachment, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	existing := int64(0)
+	if appealID != uuid.Nil {
+		var err error
+		existing, err = s.appealRepo.CountAttachments(ctx, appealID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if existing+int64(len(inputs)) > int64(s.cfg.MaxAttachments) {
+		return nil, fmt.Errorf("%w: no more than %d attachments per appeal", ErrInvalidInput, s.cfg.MaxAttachments)
+	}
+
+	attachments := make([]model.AppealAttachment, 0, len(inputs))
+	for i, input := range inputs {
+		contentType := strings.ToLower(strings.TrimSpace(input.ContentType))
+		kind, ok := model.AttachmentKindOf(contentType)
+		if !ok {
+			return nil, fmt.Errorf("%w: attachment %d: content type %q is not allowed", ErrInvalidInput, i, input.ContentType)
+		}
+
+		if input.SizeBytes <= 0 || input.SizeBytes > s.cfg.MaxAttachmentSize {
+			return nil, fmt.Errorf("%w: attachment %d: size must be from 1 to %d bytes", ErrInvalidInput, i, s.cfg.MaxAttachmentSize)
+		}
+
+		link := strings.TrimSpace(input.URL)
+		parsed, err := url.ParseRequestURI(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("%w: attachment %d: invalid url", ErrInvalidInput, i)
+		}
+
+		fileName := strings.TrimSpace(input.FileName)
+		if fileName == "" || len(fileName) > 255 {
+			return nil, fmt.Errorf("%w: attachment %d: file_name is required (up to 255 characters)", ErrInvalidInput, i)
+		}
+
+		attachments = append(attachments, model.AppealAttachment{
+			AppealID:         appealID,
+			Kind:             kind,
+			URL:              link,
+			FileName:         fileName,
+			ContentType:      contentType,
+			SizeBytes:        input.SizeBytes,
+			UploadedByUserID: principal.UserID,
+		})
+	}
+
+	return attachments, nil
+}
+
+// tripEvidence собирает фото событий камер, по которым зафиксирован рейс
+func (s *AppealService) tripEvidence(ctx context.Context, trip *model.Trip) (*TripEvidence, error) {
+	var lprIDs, volumeIDs []uuid.UUID
+	for _, id := range []*uuid.UUID{trip.EntryLprEventID, trip.ExitLprEventID} {
+		if id != nil {
+			lprIDs = append(lprIDs, *id)
+		}
+	}
+	for _, id := range []*uuid.UUID{trip.EntryVolumeEventID, trip.ExitVolumeEventID} {
+		if id != nil {
+			volumeIDs = append(volumeIDs, *id)
+		}
+	}
+
+	lprEvents, err := s.tripRepo.GetLprEvents(ctx, lprIDs)
+	if err != nil {
+		return nil, err
+	}
+	volumeEvents, err := s.tripRepo.GetVolumeEvents(ctx, volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	evidence := &TripEvidence{}
+	for i := range lprEvents {
+		event := &lprEvents[i]
+		if trip.EntryLprEventID != nil && event.ID == *trip.EntryLprEventID {
+			evidence.EntryLpr = event
+		}
+		if trip.ExitLprEventID != nil && event.ID == *trip.ExitLprEventID {
+			evidence.ExitLpr = event
+		}
+	}
+	for i := range volumeEvents {
+		event := &volumeEvents[i]
+		if trip.EntryVolumeEventID != nil && event.ID == *trip.EntryVolumeEventID {
+			evidence.EntryVolume = event
+		}
+		if trip.ExitVolumeEventID != nil && event.ID == *trip.ExitVolumeEventID {
+			evidence.ExitVolume = event
+		}
+	}
+
+	return evidence, nil
+}
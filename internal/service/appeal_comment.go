@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ticket-service/internal/model"
+)
+
+// canSeeInternalComments - внутренние заметки по обжалованиям видят только КГУ и Акимат
+func canSeeInternalComments(principal model.Principal) bool {
+	return principal.IsToo() || principal.IsAkimat()
+}
+
+// listComments возвращает переписку по обжалованию, видимую пользователю.
+// Текст и вложения удаленных сообщений не отдаем, прежний текст остается в аудите правок
+func (s *AppealService) listComments(ctx context.Context, principal model.Principal, appealID uuid.UUID) ([]model.AppealComment, error) {
+	comments, err := s.appealRepo.GetCommentsByAppealID(ctx, appealID, canSeeInternalComments(principal))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range comments {
+		if comments[i].DeletedAt != nil {
+			comments[i].Content = ""
+			comments[i].Attachments = nil
+		}
+	}
+
+	return comments, nil
+}
+
+// UpdateComment меняет текст своего комментария в пределах окна редактирования.
+// Прежний текст сохраняется в истории правок
+func (s *AppealService) UpdateComment(ctx context.Context, principal model.Principal, appealID, commentID, content string) (*model.AppealComment, error) {
+	comment, err := s.getEditableComment(ctx, principal, appealID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalidInput)
+	}
+
+	now := time.Now()
+	edit := &model.AppealCommentEdit{
+		CommentID:       comment.ID,
+		Action:          model.AppealCommentEdited,
+		PreviousContent: comment.Content,
+		EditedByUserID:  principal.UserID,
+	}
+	if err := s.appealRepo.UpdateComment(ctx, comment.ID, content, now, edit); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: comment is deleted", ErrConflict)
+		}
+		return nil, err
+	}
+
+	comment.Content = content
+	comment.EditedAt = &now
+	return comment, nil
+}
+
+// DeleteComment удаляет свой комментарий в пределах окна редактирования.
+// Сообщение остается в переписке как удаленное
+func (s *AppealService) DeleteComment(ctx context.Context, principal model.Principal, appealID, commentID string) error {
+	comment, err := s.getEditableComment(ctx, principal, appealID, commentID)
+	if err != nil {
+		return err
+	}
+
+	edit := &model.AppealCommentEdit{
+		CommentID:       comment.ID,
+		Action:          model.AppealCommentDeleted,
+		PreviousContent: comment.Content,
+		EditedByUserID:  principal.UserID,
+	}
+	if err := s.appealRepo.DeleteComment(ctx, comment.ID, time.Now(), edit); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: comment is deleted", ErrConflict)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// getEditableComment проверяет доступ к обжалованию, что комментарий принадлежит
+// пользователю и окно редактирования еще не закрыто
+func (s *AppealService) getEditableComment(ctx context.Context, principal model.Principal, appealID, commentID string) (*model.AppealComment, error) {
+	appeal, err := s.GetByID(ctx, principal, appealID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.appealRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if comment.AppealID != appeal.ID {
+		return nil, ErrNotFound
+	}
+
+	if comment.CreatedByUserID != principal.UserID {
+		return nil, ErrPermissionDenied
+	}
+
+	if comment.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: comment is deleted", ErrConflict)
+	}
+
+	if time.Since(comment.CreatedAt) > s.cfg.CommentEditWindow {
+		return nil, fmt.Errorf("%w: edit window has expired", ErrConflict)
+	}
+
+	return comment, nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ticket-service/internal/model"
+)
+
+const (
+	defaultInboxPageSize = 20
+	maxInboxPageSize     = 100
+	// commentPreviewLength - сколько символов последнего комментария показывать во входящих
+	commentPreviewLength = 200
+)
+
+// AppealCommentPreview - начало последнего комментария по обжалованию
+type AppealCommentPreview struct {
+	ID              uuid.UUID `json:"id"`
+	CreatedByUserID uuid.UUID `json:"created_by_user_id"`
+	Content         string    `json:"content"`
+	Truncated       bool      `json:"truncated"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// AppealInboxItem - обжалование во входящих водителя
+type AppealInboxItem struct {
+	Appeal         model.Appeal          `json:"appeal"`
+	LatestComment  *AppealCommentPreview `json:"latest_comment"`
+	UnreadReplies  int64                 `json:"unread_replies"`
+	HasUnreadReply bool                  `json:"has_unread_reply"`
+}
+
+// AppealInbox - страница входящих водителя со счетчиками по всем его обжалованиям
+type AppealInbox struct {
+	Items        []AppealInboxItem            `json:"items"`
+	Page         int                          `json:"page"`
+	PageSize     int                          `json:"page_size"`
+	Total        int64                        `json:"total"`
+	StatusCounts map[model.AppealStatus]int64 `json:"status_counts"`
+}
+
+// ListMine возвращает входящие водителя: его обжалования (и поданные от его имени подрядчиком) постранично (новые сверху)
+// с последним комментарием и числом непрочитанных ответов. Счетчики по статусам
+// считаются по всем обжалованиям водителя независимо от фильтра
+func (s *AppealService) ListMine(ctx context.Context, principal model.Principal, status string, page, pageSize int) (*AppealInbox, error) {
+	if !principal.IsDriver() || principal.DriverID == nil {
+		return nil, ErrPermissionDenied
+	}
+
+	var statusFilter *model.AppealStatus
+	if status != "" {
+		as := model.AppealStatus(strings.ToUpper(status))
+		if !as.IsValid() {
+			return nil, fmt.Errorf("%w: invalid status", ErrInvalidInput)
+		}
+		statusFilter = &as
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultInboxPageSize
+	}
+	if pageSize > maxInboxPageSize {
+		pageSize = maxInboxPageSize
+	}
+
+	appeals, total, err := s.appealRepo.ListInbox(ctx, principal.UserID, *principal.DriverID, statusFilter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.appealRepo.CountByStatus(ctx, principal.UserID, *principal.DriverID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(appeals))
+	for _, a := range appeals {
+		ids = append(ids, a.ID)
+	}
+
+	latest, err := s.appealRepo.GetLatestComments(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.appealRepo.CountUnread(ctx, principal.UserID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	inbox := &AppealInbox{
+		Items:        make([]AppealInboxItem, 0, len(appeals)),
+		Page:         page,
+		PageSize:     pageSize,
+		Total:        total,
+		StatusCounts: counts,
+	}
+	for _, appeal := range hideReviewers(principal, appeals) {
+		item := AppealInboxItem{
+			Appeal:        appeal,
+			UnreadReplies: unread[appeal.ID],
+		}
+		item.HasUnreadReply = item.UnreadReplies > 0
+		if comment, ok := latest[appeal.ID]; ok {
+			item.LatestComment = newCommentPreview(comment)
+		}
+		inbox.Items = append(inbox.Items, item)
+	}
+
+	return inbox, nil
+}
+
+// MarkRead отмечает всю текущую переписку по обжалованию прочитанной
+func (s *AppealService) MarkRead(ctx context.Context, principal model.Principal, appealID string) error {
+	appeal, err := s.GetByID(ctx, principal, appealID)
+	if err != nil {
+		return err
+	}
+
+	return s.appealRepo.MarkRead(ctx, appeal.ID, principal.UserID, time.Now())
+}
+
+func newCommentPreview(comment model.AppealComment) *AppealCommentPreview {
+	preview := &AppealCommentPreview{
+		ID:              comment.ID,
+		CreatedByUserID: comment.CreatedByUserID,
+		Content:         comment.Content,
+		CreatedAt:       comment.CreatedAt,
+	}
+	if runes := []rune(comment.Content); len(runes) > commentPreviewLength {
+		preview.Content = string(runes[:commentPreviewLength])
+		preview.Truncated = true
+	}
+	return preview
+}
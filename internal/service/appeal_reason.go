@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"ticket-service/internal/model"
+)
+
+var reasonCodePattern = regexp.MustCompile(`^[A-Z0-9_]{1,50}$`)
+
+// AppealReasonInput - причина обжалования для справочника
+type AppealReasonInput struct {
+	Code             string
+	TitleRu          string
+	TitleKk          string
+	TripStatuses     []string
+	EvidenceRequired bool
+	IsActive         *bool
+}
+
+// ListReasons возвращает справочник причин обжалования. Недействующие причины видит только Акимат.
+// tripStatus ограничивает справочник причинами, применимыми к рейсу с этим статусом
+func (s *AppealService) ListReasons(ctx context.Context, principal model.Principal, includeInactive bool, tripStatus string) ([]model.AppealReasonType, error) {
+	var status *model.TripStatus
+	if tripStatus != "" {
+		ts := model.TripStatus(strings.ToUpper(tripStatus))
+		if !ts.IsValid() {
+			return nil, fmt.Errorf("%w: invalid trip_status", ErrInvalidInput)
+		}
+		status = &ts
+	}
+
+	activeOnly := !includeInactive || !principal.IsAkimat()
+	return s.reasonRepo.List(ctx, activeOnly, status)
+}
+
+// CreateReason добавляет причину в справочник. Справочник ведет Акимат
+func (s *AppealService) CreateReason(ctx context.Context, principal model.Principal, input AppealReasonInput) (*model.AppealReasonType, error) {
+	if !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	if !reasonCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("%w: code must contain only A-Z, 0-9 and _ (up to 50 characters)", ErrInvalidInput)
+	}
+
+	if _, err := s.reasonRepo.GetByCode(ctx, code); err == nil {
+		return nil, fmt.Errorf("%w: reason %s already exists", ErrConflict, code)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	reason := &model.AppealReasonType{Code: code, IsActive: true}
+	if err := applyReasonInput(reason, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.reasonRepo.Create(ctx, reason); err != nil {
+		return nil, err
+	}
+
+	return reason, nil
+}
+
+// UpdateReason изменяет названия, применимость и обязательность доказательств причины.
+// Выведенная из действия причина остается в уже поданных обжалованиях
+func (s *AppealService) UpdateReason(ctx context.Context, principal model.Principal, code string, input AppealReasonInput, expectedVersion *int64) (*model.AppealReasonType, error) {
+	if !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+
+	reason, err := s.reasonRepo.GetByCode(ctx, strings.ToUpper(code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := checkVersion(expectedVersion, reason.Version); err != nil {
+		return nil, err
+	}
+
+	if err := applyReasonInput(reason, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.reasonRepo.Update(ctx, reason); err != nil {
+		return nil, mapUpdateError(err)
+	}
+
+	return reason, nil
+}
+
+func applyReasonInput(reason *model.AppealReasonType, input AppealReasonInput) error {
+	titleRu := strings.TrimSpace(input.TitleRu)
+	titleKk := strings.TrimSpace(input.TitleKk)
+	if titleRu == "" || titleKk == "" || len(titleRu) > 255 || len(titleKk) > 255 {
+		return fmt.Errorf("%w: title_ru and title_kk are required (up to 255 characters)", ErrInvalidInput)
+	}
+
+	if len(input.TripStatuses) == 0 {
+		return fmt.Errorf("%w: at least one trip status is required", ErrInvalidInput)
+	}
+
+	statuses := make([]model.AppealReasonTripStatus, 0, len(input.TripStatuses))
+	seen := make(map[model.TripStatus]struct{}, len(input.TripStatuses))
+	for _, raw := range input.TripStatuses {
+		status := model.TripStatus(strings.ToUpper(strings.TrimSpace(raw)))
+		// Рейсы без нарушений не обжалуются
+		if !status.IsValid() || status == model.TripStatusOK {
+			return fmt.Errorf("%w: invalid trip status %q", ErrInvalidInput, raw)
+		}
+		if _, ok := seen[status]; ok {
+			continue
+		}
+		seen[status] = struct{}{}
+		statuses = append(statuses, model.AppealReasonTripStatus{ReasonCode: reason.Code, TripStatus: status})
+	}
+
+	reason.TitleRu = titleRu
+	reason.TitleKk = titleKk
+	reason.TripStatuses = statuses
+	reason.EvidenceRequired = input.EvidenceRequired
+	if input.IsActive != nil {
+		reason.IsActive = *input.IsActive
+	}
+	return nil
+}
+
+// checkReason проверяет, что причина есть в справочнике, действует и применима к статусу рейса,
+// а при обязательных доказательствах - что к обжалованию приложены файлы
+func (s *AppealService) checkReason(ctx context.Context, code string, tripStatus model.TripStatus, attachments int) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	reason, err := s.reasonRepo.GetByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("%w: unknown appeal_reason_type %q", ErrInvalidInput, code)
+		}
+		return "", err
+	}
+
+	if !reason.IsActive {
+		return "", fmt.Errorf("%w: appeal_reason_type %s is no longer in use", ErrInvalidInput, code)
+	}
+	if !reason.Contests(tripStatus) {
+		return "", fmt.Errorf("%w: appeal_reason_type %s does not apply to %s trips", ErrInvalidInput, code, tripStatus)
+	}
+	if reason.EvidenceRequired && attachments == 0 {
+		return "", fmt.Errorf("%w: appeal_reason_type %s requires evidence attachments", ErrInvalidInput, code)
+	}
+
+	return reason.Code, nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ticket-service/internal/model"
+	"ticket-service/internal/repository"
+)
+
+// reviewerStatsPeriod - за какой период в нагрузке инспектора считаются решенные обжалования
+const reviewerStatsPeriod = 30 * 24 * time.Hour
+
+// ListReviewers возвращает инспекторов КГУ, между которыми распределяются обжалования
+func (s *AppealService) ListReviewers(ctx context.Context, principal model.Principal) ([]model.AppealReviewer, error) {
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+	return s.reviewerRepo.List(ctx, principal.OrgID)
+}
+
+// SaveReviewer включает инспектора в распределение обжалований своей организации или выключает из него.
+// Уже назначенные обжалования за выключенным инспектором сохраняются
+func (s *AppealService) SaveReviewer(ctx context.Context, principal model.Principal, userID string, active bool) (*model.AppealReviewer, error) {
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+
+	id, err := uuid.Parse(strings.TrimSpace(userID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user_id", ErrInvalidInput)
+	}
+
+	reviewer := &model.AppealReviewer{
+		OrgID:    principal.OrgID,
+		UserID:   id,
+		IsActive: active,
+	}
+	if err := s.reviewerRepo.Save(ctx, reviewer); err != nil {
+		return nil, err
+	}
+
+	return s.reviewerRepo.Get(ctx, principal.OrgID, id)
+}
+
+// AssignReviewer назначает рассматривающего инспектора. Без reviewerID инспектор выбирается по очереди.
+// Переназначение уже назначенного обжалования требует причины
+func (s *AppealService) AssignReviewer(ctx context.Context, principal model.Principal, appealID string, reviewerID string, reason string, expectedVersion *int64) (*model.Appeal, error) {
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+
+	appeal, err := s.GetByID(ctx, principal, appealID)
+	if err != nil {
+		return nil, err
+	}
+
+	if appeal.TicketID == nil {
+		return nil, fmt.Errorf("%w: appeal is not linked to a ticket", ErrConflict)
+	}
+	if err := checkVersion(expectedVersion, appeal.Version); err != nil {
+		return nil, err
+	}
+	if appeal.Status.IsResolution() || appeal.EscalatedAt != nil {
+		return nil, fmt.Errorf("%w: appeal is resolved or escalated to Akimat", ErrConflict)
+	}
+
+	entry := &model.AppealReviewerAssignment{
+		AppealID:           appeal.ID,
+		PreviousReviewerID: appeal.ReviewerID,
+		AssignedByUserID:   &principal.UserID,
+	}
+
+	reviewerID = strings.TrimSpace(reviewerID)
+	if reviewerID != "" {
+		entry.ReviewerID, err = uuid.Parse(reviewerID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid reviewer_id", ErrInvalidInput)
+		}
+		if appeal.ReviewerID != nil && *appeal.ReviewerID == entry.ReviewerID {
+			return nil, fmt.Errorf("%w: appeal is already assigned to this reviewer", ErrConflict)
+		}
+	}
+
+	reason = strings.TrimSpace(reason)
+	if appeal.ReviewerID != nil && reason == "" {
+		return nil, fmt.Errorf("%w: reason is required for reassignment", ErrInvalidInput)
+	}
+	if reason != "" {
+		entry.Reason = &reason
+	}
+
+	if err := s.reviewerRepo.Assign(ctx, principal.OrgID, appeal, entry, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if reviewerID != "" {
+				return nil, fmt.Errorf("%w: user is not an active reviewer of the organization", ErrInvalidInput)
+			}
+			return nil, fmt.Errorf("%w: no active reviewers to assign", ErrConflict)
+		}
+		return nil, mapUpdateError(err)
+	}
+
+	return appeal, nil
+}
+
+// createWithReviewer сохраняет новое обжалование и распределяет его по очереди между инспекторами КГУ,
+// создавшего тикет, одной транзакцией. Если инспекторов нет, обжалование остается без рассматривающего
+// и назначается вручную
+func (s *AppealService) createWithReviewer(ctx context.Context, appeal *model.Appeal) error {
+	if appeal.TicketID == nil {
+		return s.appealRepo.Create(ctx, appeal)
+	}
+
+	ticket, err := s.ticketRepo.GetByID(ctx, appeal.TicketID.String())
+	if err != nil {
+		return err
+	}
+
+	return s.reviewerRepo.CreateAppeal(ctx, ticket.CreatedByOrgID, appeal, time.Now())
+}
+
+// MyQueue возвращает открытые обжалования, назначенные инспектору, - ближайший срок первым
+func (s *AppealService) MyQueue(ctx context.Context, principal model.Principal) ([]model.Appeal, error) {
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+	return s.reviewerRepo.ListQueue(ctx, principal.UserID)
+}
+
+// ReviewerWorkload возвращает нагрузку инспекторов организации: состав распределения
+// и все, за кем числятся обжалования по тикетам организации
+func (s *AppealService) ReviewerWorkload(ctx context.Context, principal model.Principal) ([]repository.ReviewerWorkload, error) {
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+
+	now := time.Now()
+	workload, err := s.reviewerRepo.GetWorkload(ctx, principal.OrgID, now.Add(-reviewerStatsPeriod), now)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewers, err := s.reviewerRepo.List(ctx, principal.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]repository.ReviewerWorkload, 0, len(reviewers)+len(workload))
+	for _, reviewer := range reviewers {
+		stats := workload[reviewer.UserID]
+		stats.ReviewerID = reviewer.UserID
+		result = append(result, stats)
+		delete(workload, reviewer.UserID)
+	}
+	for _, stats := range workload {
+		result = append(result, stats)
+	}
+
+	return result, nil
+}
+
+// hideReviewer убирает назначенного инспектора КГУ из обжалования, которое отдается
+// водителю или подрядчику. Распределение между инспекторами - внутреннее дело КГУ
+func hideReviewer(principal model.Principal, appeal *model.Appeal) {
+	if canSeeInternalComments(principal) {
+		return
+	}
+	appeal.ReviewerID = nil
+	appeal.ReviewerAssignedAt = nil
+}
+
+// hideReviewers - hideReviewer для списка обжалований
+func hideReviewers(principal model.Principal, appeals []model.Appeal) []model.Appeal {
+	for i := range appeals {
+		hideReviewer(principal, &appeals[i])
+	}
+	return appeals
+}
@@ -3,29 +3,45 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"ticket-service/internal/config"
 	"ticket-service/internal/model"
 	"ticket-service/internal/repository"
 )
 
 type AppealService struct {
-	appealRepo *repository.AppealRepository
-	tripRepo   *repository.TripRepository
-	ticketRepo *repository.TicketRepository
+	appealRepo     *repository.AppealRepository
+	tripRepo       *repository.TripRepository
+	ticketRepo     *repository.TicketRepository
+	assignmentRepo *repository.AssignmentRepository
+	reasonRepo     *repository.AppealReasonRepository
+	reviewerRepo   *repository.AppealReviewerRepository
+	cfg            config.AppealConfig
 }
 
 func NewAppealService(
 	appealRepo *repository.AppealRepository,
 	tripRepo *repository.TripRepository,
 	ticketRepo *repository.TicketRepository,
+	assignmentRepo *repository.AssignmentRepository,
+	reasonRepo *repository.AppealReasonRepository,
+	reviewerRepo *repository.AppealReviewerRepository,
+	cfg config.AppealConfig,
 ) *AppealService {
 	return &AppealService{
-		appealRepo: appealRepo,
-		tripRepo:   tripRepo,
-		ticketRepo: ticketRepo,
+		appealRepo:     appealRepo,
+		tripRepo:       tripRepo,
+		ticketRepo:     ticketRepo,
+		assignmentRepo: assignmentRepo,
+		reasonRepo:     reasonRepo,
+		reviewerRepo:   reviewerRepo,
+		cfg:            cfg,
 	}
 }
 
@@ -33,11 +49,14 @@ type CreateAppealInput struct {
 	TripID          string
 	AppealReasonType string
 	Comment         string
+	Attachments     []AttachmentInput
+	// DriverID - водитель, от имени которого подрядчик подает обжалование по рейсу без водителя
+	DriverID        string
 }
 
 func (s *AppealService) Create(ctx context.Context, principal model.Principal, input CreateAppealInput) (*model.Appeal, error) {
-	// Только водитель может создавать обжалования
-	if !principal.IsDriver() {
+	// Обжалование подает водитель или подрядчик от имени своего водителя
+	if !principal.IsDriver() && !principal.IsContractor() {
 		return nil, ErrPermissionDenied
 	}
 
@@ -55,8 +74,9 @@ func (s *AppealService) Create(ctx context.Context, principal model.Principal, i
 		return nil, err
 	}
 
-	if trip.DriverID == nil || *trip.DriverID != *principal.DriverID {
-		return nil, ErrPermissionDenied
+	driverID, err := s.appealDriver(ctx, principal, trip, input.DriverID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Можно обжаловать только рейсы с нарушениями
@@ -64,6 +84,35 @@ func (s *AppealService) Create(ctx context.Context, principal model.Principal, i
 		return nil, ErrConflict
 	}
 
+	// Обжаловать можно только в течение окна подачи после рейса
+	now := time.Now()
+	if now.After(trip.EntryAt.Add(s.cfg.FilingWindow)) {
+		return nil, fmt.Errorf("%w: filing window for this trip has expired", ErrConflict)
+	}
+	reviewDueAt := now.Add(s.cfg.ReviewTimeout)
+
+	// По рейсу - одно обжалование; после отказа - только обжалование решения в Акимате
+	existing, err := s.appealRepo.ListByTripID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		last := existing[len(existing)-1]
+		message := "trip already has an appeal"
+		if last.Status.IsResolution() {
+			message = "trip appeal is already decided; a rejection can only be escalated to Akimat"
+		}
+		return nil, &ConflictError{
+			Message: message,
+			Details: map[string]interface{}{"appeal_id": last.ID, "status": last.Status},
+		}
+	}
+
+	reasonType, err := s.checkReason(ctx, input.AppealReasonType, trip.Status, len(input.Attachments))
+	if err != nil {
+		return nil, err
+	}
+
 	var ticketID *uuid.UUID
 	if trip.TicketID != nil {
 		ticketID = trip.TicketID
@@ -73,16 +122,147 @@ func (s *AppealService) Create(ctx context.Context, principal model.Principal, i
 		TripID:          &tripID,
 		TicketID:        ticketID,
 		CreatedByUserID: principal.UserID,
+		CreatedByRole:   principal.Role,
+		DriverID:        driverID,
 		Status:          model.AppealStatusSubmitted,
 		Reason:          string(trip.Status), // Нарушение из статуса рейса
-		AppealReasonType: &input.AppealReasonType,
+		AppealReasonType: &reasonType,
 		Comment:         input.Comment,
+		ReviewDueAt:     &reviewDueAt,
+	}
+
+	appeal.Attachments, err = s.prepareAttachments(ctx, principal, uuid.Nil, input.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.createWithReviewer(ctx, appeal); err != nil {
+		// Параллельно подано другое обжалование по этому рейсу
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: trip already has an open appeal", ErrConflict)
+		}
+		return nil, err
+	}
+
+	hideReviewer(principal, appeal)
+	return appeal, nil
+}
+
+// appealDriver определяет водителя, чей рейс обжалуется. Водитель обжалует только свои рейсы.
+// Подрядчик - рейсы своих тикетов; если водитель рейса не известен (рейс без назначения),
+// подрядчик может указать его сам
+func (s *AppealService) appealDriver(ctx context.Context, principal model.Principal, trip *model.Trip, driverID string) (*uuid.UUID, error) {
+	if principal.IsDriver() {
+		if principal.DriverID == nil || trip.DriverID == nil || *trip.DriverID != *principal.DriverID {
+			return nil, ErrPermissionDenied
+		}
+		return trip.DriverID, nil
+	}
+
+	if trip.TicketID == nil {
+		return nil, ErrPermissionDenied
+	}
+	ticket, err := s.ticketRepo.GetByID(ctx, trip.TicketID.String())
+	if err != nil {
+		return nil, err
+	}
+	if ticket.ContractorID != principal.OrgID {
+		return nil, ErrPermissionDenied
+	}
+
+	driverID = strings.TrimSpace(driverID)
+	if driverID == "" {
+		return trip.DriverID, nil
+	}
+	id, err := uuid.Parse(driverID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid driver_id", ErrInvalidInput)
+	}
+	if trip.DriverID != nil && *trip.DriverID != id {
+		return nil, fmt.Errorf("%w: driver_id does not match the trip driver", ErrInvalidInput)
+	}
+	return &id, nil
+}
+
+// isAppellant сообщает, что пользователь - сторона обжалования: подавший его
+// или водитель, от имени которого оно подано
+func isAppellant(principal model.Principal, appeal *model.Appeal) bool {
+	if appeal.CreatedByUserID == principal.UserID {
+		return true
+	}
+	return principal.IsDriver() && principal.DriverID != nil && appeal.DriverID != nil && *appeal.DriverID == *principal.DriverID
+}
+
+// Escalate обжалует в Акимате отказ по обжалованию водителя. Новое обжалование ссылается
+// на исходное и сразу передается Акимату, КГУ его не рассматривает. Решение Акимата окончательное,
+// решение можно обжаловать один раз и в пределах окна подачи после отказа
+func (s *AppealService) Escalate(ctx context.Context, principal model.Principal, appealID string, comment string, attachments []AttachmentInput) (*model.Appeal, error) {
+	if !principal.IsDriver() && !principal.IsContractor() {
+		return nil, ErrPermissionDenied
+	}
+
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return nil, fmt.Errorf("%w: comment is required", ErrInvalidInput)
+	}
+
+	original, err := s.GetByID(ctx, principal, appealID)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.TripID == nil || original.Decision == nil || *original.Decision != model.AppealStatusRejected || !original.Status.IsResolution() {
+		return nil, fmt.Errorf("%w: only a rejected appeal can be escalated", ErrConflict)
+	}
+	if original.EscalatedAt != nil {
+		return nil, fmt.Errorf("%w: appeal was decided by Akimat, the decision is final", ErrConflict)
+	}
+
+	now := time.Now()
+	if original.ResolvedAt != nil && now.After(original.ResolvedAt.Add(s.cfg.FilingWindow)) {
+		return nil, fmt.Errorf("%w: filing window for escalation has expired", ErrConflict)
+	}
+
+	chain, err := s.appealRepo.ListByTripID(ctx, *original.TripID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range chain {
+		if a.ParentAppealID != nil && *a.ParentAppealID == original.ID {
+			return nil, &ConflictError{
+				Message: "appeal is already escalated",
+				Details: map[string]interface{}{"appeal_id": a.ID, "status": a.Status},
+			}
+		}
+	}
+
+	appeal := &model.Appeal{
+		TripID:           original.TripID,
+		TicketID:         original.TicketID,
+		CreatedByUserID:  principal.UserID,
+		CreatedByRole:    principal.Role,
+		DriverID:         original.DriverID,
+		Status:           model.AppealStatusSubmitted,
+		Reason:           original.Reason,
+		AppealReasonType: original.AppealReasonType,
+		Comment:          comment,
+		EscalatedAt:      &now,
+		ParentAppealID:   &original.ID,
+	}
+
+	appeal.Attachments, err = s.prepareAttachments(ctx, principal, uuid.Nil, attachments)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := s.appealRepo.Create(ctx, appeal); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: appeal is already escalated or trip has an open appeal", ErrConflict)
+		}
 		return nil, err
 	}
 
+	hideReviewer(principal, appeal)
 	return appeal, nil
 }
 
@@ -107,13 +287,24 @@ func (s *AppealService) ListByTicketID(ctx context.Context, principal model.Prin
 			return nil, ErrPermissionDenied
 		}
 	} else if principal.IsDriver() {
+		if principal.DriverID == nil {
+			return nil, ErrPermissionDenied
+		}
 		// Водитель видит только свои обжалования
-		return s.appealRepo.ListByUserID(ctx, principal.UserID)
+		appeals, err := s.appealRepo.ListByUserID(ctx, principal.UserID, *principal.DriverID)
+		if err != nil {
+			return nil, err
+		}
+		return hideReviewers(principal, appeals), nil
 	} else {
 		return nil, ErrPermissionDenied
 	}
 
-	return s.appealRepo.ListByTicketID(ctx, ticket.ID)
+	appeals, err := s.appealRepo.ListByTicketID(ctx, ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+	return hideReviewers(principal, appeals), nil
 }
 
 func (s *AppealService) GetByID(ctx context.Context, principal model.Principal, id string) (*model.Appeal, error) {
@@ -149,62 +340,332 @@ func (s *AppealService) GetByID(ctx context.Context, principal model.Principal,
 			}
 		}
 	} else if principal.IsDriver() {
-		if appeal.CreatedByUserID != principal.UserID {
+		if !isAppellant(principal, appeal) {
 			return nil, ErrPermissionDenied
 		}
 	} else {
 		return nil, ErrPermissionDenied
 	}
 
+	appeal.Attachments, err = s.appealRepo.ListAttachments(ctx, appeal.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return appeal, nil
 }
 
-func (s *AppealService) UpdateStatus(ctx context.Context, principal model.Principal, id string, status model.AppealStatus, adminResponse *string) error {
+// List возвращает обжалования для рассмотрения: Акимату - все, КГУ - по своим тикетам
+func (s *AppealService) List(ctx context.Context, principal model.Principal, filter repository.AppealListFilter) ([]model.Appeal, error) {
+	if principal.IsAkimat() {
+		// Акимат видит все
+	} else if principal.IsToo() {
+		orgID := principal.OrgID.String()
+		filter.CreatedByOrgID = &orgID
+	} else if principal.IsContractor() {
+		orgID := principal.OrgID.String()
+		filter.ContractorID = &orgID
+	} else {
+		return nil, ErrPermissionDenied
+	}
+
+	appeals, err := s.appealRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return hideReviewers(principal, appeals), nil
+}
+
+// AppealDetails - обжалование вместе с обжалуемым рейсом, фото камер по рейсу и перепиской
+type AppealDetails struct {
+	Appeal       *model.Appeal            `json:"appeal"`
+	Trip         *model.Trip              `json:"trip"`
+	TripEvidence *TripEvidence            `json:"trip_evidence"`
+	TripHistory  []model.TripStatusChange `json:"trip_history"`
+	// Chain - все обжалования по рейсу, включая обжалования решений в Акимате
+	Chain        []model.Appeal           `json:"chain"`
+	// ReviewerHistory - назначения и переназначения рассматривающих инспекторов
+	ReviewerHistory []model.AppealReviewerAssignment `json:"reviewer_history"`
+	Comments     []model.AppealComment    `json:"comments"`
+}
+
+func (s *AppealService) GetDetails(ctx context.Context, principal model.Principal, id string) (*AppealDetails, error) {
+	appeal, err := s.GetByID(ctx, principal, id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &AppealDetails{Appeal: appeal}
+
+	if appeal.TripID != nil {
+		trip, err := s.tripRepo.GetByID(ctx, appeal.TripID.String())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		details.Trip = trip
+
+		if trip != nil {
+			details.TripEvidence, err = s.tripEvidence(ctx, trip)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		details.TripHistory, err = s.tripRepo.ListStatusHistory(ctx, *appeal.TripID)
+		if err != nil {
+			return nil, err
+		}
+
+		details.Chain, err = s.appealRepo.ListByTripID(ctx, *appeal.TripID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	details.Comments, err = s.listComments(ctx, principal, appeal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Распределение между инспекторами - внутреннее дело КГУ, водителю и подрядчику не отдаем
+	if !canSeeInternalComments(principal) {
+		details.ReviewerHistory = []model.AppealReviewerAssignment{}
+		hideReviewer(principal, appeal)
+		hideReviewers(principal, details.Chain)
+		return details, nil
+	}
+
+	details.ReviewerHistory, err = s.reviewerRepo.ListAssignments(ctx, appeal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
+
+// ReviewAppealInput - решение по обжалованию. TripStatus и TicketAssignmentID применяются
+// к обжалуемому рейсу только при одобрении
+type ReviewAppealInput struct {
+	Status             model.AppealStatus
+	AdminResponse      *string
+	TripStatus         *string
+	TicketAssignmentID *string
+}
+
+// AppealReviewResult - обжалование после рассмотрения. При одобрении содержит
+// исправленный рейс и пересчитанные метрики тикета
+type AppealReviewResult struct {
+	Appeal        *model.Appeal            `json:"appeal"`
+	Trip          *model.Trip              `json:"trip,omitempty"`
+	TicketMetrics *repository.TicketMetrics `json:"ticket_metrics,omitempty"`
+}
+
+// UpdateStatus переводит обжалование по допустимому переходу статусов. Итоговые статусы
+// (APPROVED, REJECTED, CLOSED) фиксируют время решения. Одобрение исправляет рейс:
+// выставляет новый статус (по умолчанию OK) и при необходимости привязывает рейс к назначению
+// того же тикета; исходный статус сохраняется в истории рейса
+func (s *AppealService) UpdateStatus(ctx context.Context, principal model.Principal, id string, input ReviewAppealInput, expectedVersion *int64) (*AppealReviewResult, error) {
 	// Только KGU ZKH и Акимат могут обновлять статус обжалования
 	if !principal.IsToo() && !principal.IsAkimat() {
-		return ErrPermissionDenied
+		return nil, ErrPermissionDenied
+	}
+
+	if input.Status != model.AppealStatusApproved && (input.TripStatus != nil || input.TicketAssignmentID != nil) {
+		return nil, fmt.Errorf("%w: trip_status and ticket_assignment_id are allowed only for APPROVED", ErrInvalidInput)
 	}
 
 	appeal, err := s.appealRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrNotFound
+			return nil, ErrNotFound
 		}
-		return err
+		return nil, err
+	}
+
+	// После эскалации обжалование рассматривает только Акимат
+	if principal.IsToo() && appeal.EscalatedAt != nil {
+		return nil, fmt.Errorf("%w: appeal is escalated to Akimat", ErrPermissionDenied)
 	}
 
 	// Проверяем права доступа
 	if principal.IsToo() && appeal.TicketID != nil {
 		ticket, err := s.ticketRepo.GetByID(ctx, appeal.TicketID.String())
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if ticket.CreatedByOrgID != principal.OrgID {
-			return ErrPermissionDenied
+			return nil, ErrPermissionDenied
+		}
+	}
+
+	if err := checkVersion(expectedVersion, appeal.Version); err != nil {
+		return nil, err
+	}
+
+	if !appeal.Status.CanTransitionTo(input.Status) {
+		return nil, fmt.Errorf("%w: appeal cannot move from %s to %s", ErrConflict, appeal.Status, input.Status)
+	}
+
+	var (
+		trip   *model.Trip
+		change *model.TripStatusChange
+	)
+	if input.Status == model.AppealStatusApproved && appeal.TripID != nil {
+		trip, change, err = s.correctTrip(ctx, principal, appeal, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if input.AdminResponse != nil {
+		appeal.AdminResponse = input.AdminResponse
+	}
+	s.setStatus(appeal, input.Status, time.Now())
+
+	if err := s.appealRepo.Resolve(ctx, appeal, trip, change); err != nil {
+		return nil, mapUpdateError(err)
+	}
+
+	result := &AppealReviewResult{Appeal: appeal, Trip: trip}
+	if trip != nil && trip.TicketID != nil {
+		result.TicketMetrics, err = s.ticketRepo.GetTicketMetrics(ctx, *trip.TicketID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// setStatus переводит обжалование в статус и пересчитывает сроки: запрос информации дает
+// водителю срок на ответ, возврат на рассмотрение - новый срок рассмотрения
+func (s *AppealService) setStatus(appeal *model.Appeal, status model.AppealStatus, now time.Time) {
+	switch status {
+	case model.AppealStatusNeedInfo:
+		responseDueAt := now.Add(s.cfg.ResponseTimeout)
+		appeal.ResponseDueAt = &responseDueAt
+	case model.AppealStatusUnderReview:
+		if appeal.Status == model.AppealStatusNeedInfo {
+			reviewDueAt := now.Add(s.cfg.ReviewTimeout)
+			appeal.ReviewDueAt = &reviewDueAt
 		}
+		appeal.ResponseDueAt = nil
 	}
 
+	if status == model.AppealStatusApproved || status == model.AppealStatusRejected {
+		decision := status
+		appeal.Decision = &decision
+	}
+	if status.IsResolution() && appeal.ResolvedAt == nil {
+		appeal.ResolvedAt = &now
+	}
 	appeal.Status = status
-	if adminResponse != nil {
-		appeal.AdminResponse = adminResponse
+}
+
+// ProcessDeadlines передает Акимату обжалования с истекшим сроком рассмотрения и закрывает
+// обжалования, по которым водитель не ответил на запрос информации. Вызывается планировщиком
+func (s *AppealService) ProcessDeadlines(ctx context.Context) (escalated int, closed int, err error) {
+	now := time.Now()
+
+	overdue, err := s.appealRepo.ListReviewOverdue(ctx, now, s.cfg.ReviewTimeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range overdue {
+		appeal := &overdue[i]
+		appeal.EscalatedAt = &now
+		if err := s.appealRepo.Update(ctx, appeal); err != nil {
+			// Обжалование изменили параллельно - проверим его на следующем проходе
+			if errors.Is(err, repository.ErrVersionConflict) {
+				continue
+			}
+			return escalated, closed, err
+		}
+		escalated++
+	}
+
+	unanswered, err := s.appealRepo.ListResponseOverdue(ctx, now, s.cfg.ResponseTimeout)
+	if err != nil {
+		return escalated, closed, err
+	}
+	for i := range unanswered {
+		appeal := &unanswered[i]
+		s.setStatus(appeal, model.AppealStatusClosed, now)
+		if err := s.appealRepo.Update(ctx, appeal); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				continue
+			}
+			return escalated, closed, err
+		}
+		closed++
 	}
 
-	return s.appealRepo.Update(ctx, appeal)
+	return escalated, closed, nil
 }
 
-func (s *AppealService) AddComment(ctx context.Context, principal model.Principal, appealID string, content string) error {
+// correctTrip готовит исправление обжалуемого рейса и запись об исходном статусе
+func (s *AppealService) correctTrip(ctx context.Context, principal model.Principal, appeal *model.Appeal, input ReviewAppealInput) (*model.Trip, *model.TripStatusChange, error) {
+	trip, err := s.tripRepo.GetByID(ctx, appeal.TripID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	status := model.TripStatusOK
+	if input.TripStatus != nil {
+		status = model.TripStatus(strings.ToUpper(strings.TrimSpace(*input.TripStatus)))
+		if !status.IsValid() {
+			return nil, nil, fmt.Errorf("%w: invalid trip_status", ErrInvalidInput)
+		}
+	}
+
+	change := &model.TripStatusChange{
+		TripID:           trip.ID,
+		FromStatus:       trip.Status,
+		ToStatus:         status,
+		FromAssignmentID: trip.TicketAssignmentID,
+		ToAssignmentID:   trip.TicketAssignmentID,
+		AppealID:         &appeal.ID,
+		ChangedByUserID:  principal.UserID,
+	}
+
+	if input.TicketAssignmentID != nil {
+		assignment, err := s.assignmentRepo.GetByID(ctx, strings.TrimSpace(*input.TicketAssignmentID))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil, fmt.Errorf("%w: assignment not found", ErrInvalidInput)
+			}
+			return nil, nil, err
+		}
+		if trip.TicketID == nil || assignment.TicketID != *trip.TicketID {
+			return nil, nil, fmt.Errorf("%w: assignment belongs to another ticket", ErrInvalidInput)
+		}
+		trip.TicketAssignmentID = &assignment.ID
+		trip.DriverID = &assignment.DriverID
+		change.ToAssignmentID = &assignment.ID
+	}
+
+	trip.Status = status
+	return trip, change, nil
+}
+
+// AddComment добавляет комментарий к обжалованию. Внутренние заметки (INTERNAL) оставляют
+// только КГУ и Акимат, водителю и подрядчику они не показываются
+func (s *AppealService) AddComment(ctx context.Context, principal model.Principal, appealID string, content string, visibility model.AppealCommentVisibility, attachments []AttachmentInput) (*model.AppealComment, error) {
 	appeal, err := s.appealRepo.GetByID(ctx, appealID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrNotFound
+			return nil, ErrNotFound
 		}
-		return err
+		return nil, err
 	}
 
 	// Проверяем права доступа
 	if principal.IsDriver() {
-		if appeal.CreatedByUserID != principal.UserID {
-			return ErrPermissionDenied
+		if !isAppellant(principal, appeal) {
+			return nil, ErrPermissionDenied
 		}
 	} else if principal.IsToo() || principal.IsAkimat() {
 		// KGU ZKH и Акимат могут комментировать
@@ -212,23 +673,51 @@ func (s *AppealService) AddComment(ctx context.Context, principal model.Principa
 		if appeal.TicketID != nil {
 			ticket, err := s.ticketRepo.GetByID(ctx, appeal.TicketID.String())
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if ticket.ContractorID != principal.OrgID {
-				return ErrPermissionDenied
+				return nil, ErrPermissionDenied
 			}
 		}
 	} else {
-		return ErrPermissionDenied
+		return nil, ErrPermissionDenied
+	}
+
+	if visibility == "" {
+		visibility = model.AppealCommentPublic
+	}
+	if !visibility.IsValid() {
+		return nil, fmt.Errorf("%w: unknown visibility", ErrInvalidInput)
+	}
+	if visibility == model.AppealCommentInternal && !canSeeInternalComments(principal) {
+		return nil, ErrPermissionDenied
 	}
 
 	comment := &model.AppealComment{
 		AppealID:       appeal.ID,
 		CreatedByUserID: principal.UserID,
 		Content:        content,
+		Visibility:     visibility,
+	}
+
+	comment.Attachments, err = s.prepareAttachments(ctx, principal, appeal.ID, attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ответ водителя или подрядчика на запрос информации возвращает обжалование на рассмотрение.
+	// Комментарий, отметка о прочтении автором и смена статуса сохраняются вместе
+	var reopened *model.Appeal
+	if (principal.IsDriver() || principal.IsContractor()) && appeal.Status == model.AppealStatusNeedInfo {
+		s.setStatus(appeal, model.AppealStatusUnderReview, time.Now())
+		reopened = appeal
+	}
+
+	if err := s.appealRepo.AddComment(ctx, comment, reopened); err != nil {
+		return nil, mapUpdateError(err)
 	}
 
-	return s.appealRepo.AddComment(ctx, comment)
+	return comment, nil
 }
 
 func (s *AppealService) GetComments(ctx context.Context, principal model.Principal, appealID string) ([]model.AppealComment, error) {
@@ -242,7 +731,7 @@ func (s *AppealService) GetComments(ctx context.Context, principal model.Princip
 
 	// Проверяем права доступа (та же логика, что и в GetByID)
 	if principal.IsDriver() {
-		if appeal.CreatedByUserID != principal.UserID {
+		if !isAppellant(principal, appeal) {
 			return nil, ErrPermissionDenied
 		}
 	} else if principal.IsToo() || principal.IsAkimat() {
@@ -261,6 +750,6 @@ func (s *AppealService) GetComments(ctx context.Context, principal model.Princip
 		return nil, ErrPermissionDenied
 	}
 
-	return s.appealRepo.GetCommentsByAppealID(ctx, appeal.ID)
+	return s.listComments(ctx, principal, appeal.ID)
 }
 
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"ticket-service/internal/model"
+)
+
+// maxBulkAssignments ограничивает размер одного пакетного запроса
+const maxBulkAssignments = 100
+
+// BulkAssignmentItem - одна пара водитель + техника в пакетном назначении
+type BulkAssignmentItem struct {
+	DriverID      string
+	VehicleID     string
+	AllowOverlap  bool
+	OverlapReason string
+}
+
+// BulkUnassignItem - одно назначение в пакетном снятии
+type BulkUnassignItem struct {
+	AssignmentID    string
+	ExpectedVersion *int64
+}
+
+// BulkItemResult - результат обработки одного элемента пакета
+type BulkItemResult struct {
+	Index      int                     `json:"index"`
+	Success    bool                    `json:"success"`
+	Assignment *model.TicketAssignment `json:"assignment,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	Details    interface{}             `json:"details,omitempty"`
+}
+
+// BulkResult - итог пакетной операции. Пакет применяется целиком или не применяется вовсе:
+// при ошибке хотя бы в одном элементе результат возвращается в деталях конфликта
+type BulkResult struct {
+	Items []BulkItemResult `json:"items"`
+}
+
+// BulkCreate проверяет все пары (принадлежность тикета, повторы внутри пакета, занятость,
+// рабочее время) и создает назначения одной транзакцией. Если хотя бы одна пара не прошла
+// проверку, не создается ни одно назначение, а ошибки возвращаются по каждому элементу
+func (s *AssignmentService) BulkCreate(ctx context.Context, principal model.Principal, ticketID string, items []BulkAssignmentItem) (*BulkResult, error) {
+	if !principal.IsContractor() {
+		return nil, ErrPermissionDenied
+	}
+
+	if len(items) == 0 || len(items) > maxBulkAssignments {
+		return nil, fmt.Errorf("%w: from 1 to %d items are required", ErrInvalidInput, maxBulkAssignments)
+	}
+
+	if _, err := uuid.Parse(ticketID); err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	ticket, err := s.getAssignableTicket(ctx, principal, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{Items: make([]BulkItemResult, len(items))}
+	assignments := make([]*model.TicketAssignment, 0, len(items))
+	seenDrivers := make(map[uuid.UUID]int)
+	seenVehicles := make(map[uuid.UUID]int)
+	failed := false
+
+	for i, item := range items {
+		res := &result.Items[i]
+		res.Index = i
+
+		driverID, errD := uuid.Parse(strings.TrimSpace(item.DriverID))
+		vehicleID, errV := uuid.Parse(strings.TrimSpace(item.VehicleID))
+		if errD != nil || errV != nil {
+			setBulkItemError(res, fmt.Errorf("%w: invalid driver_id or vehicle_id", ErrInvalidInput))
+			failed = true
+			continue
+		}
+
+		// Один водитель и одна техника - не больше одного раза в пакете
+		if j, ok := seenDrivers[driverID]; ok {
+			setBulkItemError(res, fmt.Errorf("%w: driver is duplicated in item %d", ErrConflict, j))
+			failed = true
+			continue
+		}
+		if j, ok := seenVehicles[vehicleID]; ok {
+			setBulkItemError(res, fmt.Errorf("%w: vehicle is duplicated in item %d", ErrConflict, j))
+			failed = true
+			continue
+		}
+		seenDrivers[driverID] = i
+		seenVehicles[vehicleID] = i
+
+		assignment, err := s.prepareAssignment(ctx, ticket, driverID, vehicleID, item.AllowOverlap, item.OverlapReason)
+		if err != nil {
+			if !isBulkItemError(err) {
+				return nil, err
+			}
+			setBulkItemError(res, err)
+			failed = true
+			continue
+		}
+
+		res.Assignment = assignment
+		assignments = append(assignments, assignment)
+	}
+
+	if failed {
+		return nil, rejectBulk(result)
+	}
+
+	if err := s.assignmentRepo.CreateBatch(ctx, assignments); err != nil {
+		return nil, err
+	}
+
+	for i := range result.Items {
+		result.Items[i].Success = true
+	}
+	return result, nil
+}
+
+// BulkDelete снимает несколько назначений подрядчика одной транзакцией.
+// Если хотя бы одно назначение нельзя снять, не снимается ни одно
+func (s *AssignmentService) BulkDelete(ctx context.Context, principal model.Principal, items []BulkUnassignItem, reason string) (*BulkResult, error) {
+	if !principal.IsContractor() {
+		return nil, ErrPermissionDenied
+	}
+
+	if len(items) == 0 || len(items) > maxBulkAssignments {
+		return nil, fmt.Errorf("%w: from 1 to %d items are required", ErrInvalidInput, maxBulkAssignments)
+	}
+
+	result := &BulkResult{Items: make([]BulkItemResult, len(items))}
+	ids := make([]uuid.UUID, len(items))
+	failed := false
+
+	for i, item := range items {
+		result.Items[i].Index = i
+		id, err := uuid.Parse(strings.TrimSpace(item.AssignmentID))
+		if err != nil {
+			setBulkItemError(&result.Items[i], fmt.Errorf("%w: invalid assignment_id", ErrInvalidInput))
+			failed = true
+			continue
+		}
+		ids[i] = id
+	}
+
+	found, err := s.assignmentRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*model.TicketAssignment, len(found))
+	ticketIDs := make([]uuid.UUID, 0, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
+		ticketIDs = append(ticketIDs, found[i].TicketID)
+	}
+
+	tickets, err := s.ticketRepo.GetByIDs(ctx, ticketIDs)
+	if err != nil {
+		return nil, err
+	}
+	contractorByTicket := make(map[uuid.UUID]uuid.UUID, len(tickets))
+	for _, t := range tickets {
+		contractorByTicket[t.ID] = t.ContractorID
+	}
+
+	reason = strings.TrimSpace(reason)
+	seen := make(map[uuid.UUID]int)
+	assignments := make([]*model.TicketAssignment, 0, len(items))
+
+	for i, item := range items {
+		res := &result.Items[i]
+		if res.Error != "" {
+			continue
+		}
+
+		if j, ok := seen[ids[i]]; ok {
+			setBulkItemError(res, fmt.Errorf("%w: assignment is duplicated in item %d", ErrInvalidInput, j))
+			failed = true
+			continue
+		}
+		seen[ids[i]] = i
+
+		assignment, ok := byID[ids[i]]
+		if !ok {
+			setBulkItemError(res, ErrNotFound)
+			failed = true
+			continue
+		}
+		if contractorByTicket[assignment.TicketID] != principal.OrgID {
+			setBulkItemError(res, ErrPermissionDenied)
+			failed = true
+			continue
+		}
+		if err := checkVersion(item.ExpectedVersion, assignment.Version); err != nil {
+			setBulkItemError(res, err)
+			failed = true
+			continue
+		}
+		if !assignment.IsActive {
+			setBulkItemError(res, fmt.Errorf("%w: assignment is already inactive", ErrConflict))
+			failed = true
+			continue
+		}
+
+		assignment.UnassignedBy = &principal.UserID
+		if reason != "" {
+			assignment.UnassignReason = &reason
+		}
+		res.Assignment = assignment
+		assignments = append(assignments, assignment)
+	}
+
+	if failed {
+		return nil, rejectBulk(result)
+	}
+
+	if err := s.assignmentRepo.DeleteBatch(ctx, assignments); err != nil {
+		return nil, mapUpdateError(err)
+	}
+
+	for i := range result.Items {
+		result.Items[i].Success = true
+	}
+	return result, nil
+}
+
+// rejectBulk отклоняет пакет целиком, возвращая результаты проверки по каждому элементу
+func rejectBulk(result *BulkResult) error {
+	for i := range result.Items {
+		result.Items[i].Assignment = nil
+	}
+	return &ConflictError{
+		Message: "bulk operation rejected: some items failed validation",
+		Details: result,
+	}
+}
+
+// isBulkItemError отличает ошибки проверки элемента от внутренних ошибок
+func isBulkItemError(err error) bool {
+	return errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrConflict) ||
+		errors.Is(err, ErrNotFound) || errors.Is(err, ErrPermissionDenied) ||
+		errors.Is(err, ErrPreconditionFailed)
+}
+
+func setBulkItemError(res *BulkItemResult, err error) {
+	res.Error = err.Error()
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		res.Details = conflict.Details
+	}
+}
@@ -3,11 +3,15 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"ticket-service/internal/config"
 	"ticket-service/internal/model"
 	"ticket-service/internal/repository"
 )
@@ -15,12 +19,18 @@ import (
 type AssignmentService struct {
 	assignmentRepo *repository.AssignmentRepository
 	ticketRepo     *repository.TicketRepository
+	tripRepo       *repository.TripRepository
+	workTime       workTime
+	shiftCfg       config.ShiftConfig
 }
 
-func NewAssignmentService(assignmentRepo *repository.AssignmentRepository, ticketRepo *repository.TicketRepository) *AssignmentService {
+func NewAssignmentService(assignmentRepo *repository.AssignmentRepository, ticketRepo *repository.TicketRepository, tripRepo *repository.TripRepository, shiftRepo *repository.DriverShiftRepository, shiftCfg config.ShiftConfig) *AssignmentService {
 	return &AssignmentService{
 		assignmentRepo: assignmentRepo,
 		ticketRepo:     ticketRepo,
+		tripRepo:       tripRepo,
+		workTime:       newWorkTime(shiftRepo, assignmentRepo, tripRepo),
+		shiftCfg:       shiftCfg,
 	}
 }
 
@@ -28,6 +38,27 @@ type CreateAssignmentInput struct {
 	TicketID  string
 	DriverID  string
 	VehicleID string
+	// AllowOverlap разрешает назначить водителя/технику поверх пересекающихся работ
+	AllowOverlap  bool
+	OverlapReason string
+}
+
+// BookingOverlap описывает назначение, которое занимает водителя или технику в то же время
+type BookingOverlap struct {
+	TicketID       uuid.UUID          `json:"ticket_id"`
+	AssignmentID   uuid.UUID          `json:"assignment_id"`
+	TicketStatus   model.TicketStatus `json:"ticket_status"`
+	PlannedStartAt time.Time          `json:"planned_start_at"`
+	PlannedEndAt   time.Time          `json:"planned_end_at"`
+	DriverBusy     bool               `json:"driver_busy"`
+	VehicleBusy    bool               `json:"vehicle_busy"`
+}
+
+// BookingConflict - детали конфликта занятости водителя и техники
+type BookingConflict struct {
+	DriverID    uuid.UUID        `json:"driver_id"`
+	VehicleID   uuid.UUID        `json:"vehicle_id"`
+	Overlapping []BookingOverlap `json:"overlapping"`
 }
 
 func (s *AssignmentService) Create(ctx context.Context, principal model.Principal, input CreateAssignmentInput) (*model.TicketAssignment, error) {
@@ -36,8 +67,7 @@ func (s *AssignmentService) Create(ctx context.Context, principal model.Principa
 		return nil, ErrPermissionDenied
 	}
 
-	ticketID, err := uuid.Parse(input.TicketID)
-	if err != nil {
+	if _, err := uuid.Parse(input.TicketID); err != nil {
 		return nil, ErrInvalidInput
 	}
 
@@ -51,8 +81,27 @@ func (s *AssignmentService) Create(ctx context.Context, principal model.Principa
 		return nil, ErrInvalidInput
 	}
 
+	ticket, err := s.getAssignableTicket(ctx, principal, input.TicketID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err := s.prepareAssignment(ctx, ticket, driverID, vehicleID, input.AllowOverlap, input.OverlapReason)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.assignmentRepo.Create(ctx, assignment); err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// getAssignableTicket загружает тикет подрядчика, на который можно назначать экипажи
+func (s *AssignmentService) getAssignableTicket(ctx context.Context, principal model.Principal, ticketID string) (*model.Ticket, error) {
 	// Проверяем, что тикет принадлежит подрядчику
-	ticket, err := s.ticketRepo.GetByID(ctx, input.TicketID)
+	ticket, err := s.ticketRepo.GetByID(ctx, ticketID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotFound
@@ -64,22 +113,231 @@ func (s *AssignmentService) Create(ctx context.Context, principal model.Principa
 		return nil, ErrPermissionDenied
 	}
 
+	// Назначать экипажи можно только после подтверждения тикета подрядчиком
+	if ticket.Acceptance != model.TicketAcceptanceAccepted {
+		return nil, fmt.Errorf("%w: ticket is not accepted", ErrConflict)
+	}
+
+	return ticket, nil
+}
+
+// prepareAssignment проверяет занятость и рабочее время экипажа и собирает новое назначение
+func (s *AssignmentService) prepareAssignment(ctx context.Context, ticket *model.Ticket, driverID, vehicleID uuid.UUID, allowOverlap bool, reason string) (*model.TicketAssignment, error) {
+	// Проверяем, что водитель и техника не заняты на других работах в это же время
+	overlapReason, err := s.checkBookings(ctx, ticket, driverID, vehicleID, uuid.Nil, allowOverlap, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем лимиты рабочего времени водителя
+	hoursWarning, err := s.checkDriverHours(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+
 	assignment := &model.TicketAssignment{
-		TicketID:         ticketID,
+		TicketID:         ticket.ID,
 		DriverID:         driverID,
 		VehicleID:        vehicleID,
 		DriverMarkStatus: model.DriverMarkStatusNotStarted,
 		IsActive:         true,
+		OverlapReason:    overlapReason,
+	}
+	if hoursWarning != "" {
+		assignment.Warnings = append(assignment.Warnings, hoursWarning)
 	}
 
-	if err := s.assignmentRepo.Create(ctx, assignment); err != nil {
+	return assignment, nil
+}
+
+// checkDriverHours проверяет лимиты рабочего времени водителя. В режиме блокировки
+// превышение возвращает конфликт, иначе - текст предупреждения
+func (s *AssignmentService) checkDriverHours(ctx context.Context, driverID uuid.UUID) (string, error) {
+	hos, err := s.workTime.hoursOfService(ctx, driverID, s.shiftCfg)
+	if err != nil {
+		return "", err
+	}
+	if !hos.Exceeded() {
+		return "", nil
+	}
+	if s.shiftCfg.BlockOnLimit {
+		return "", &ConflictError{
+			Message: "driver has exceeded working hours limit",
+			Details: hos,
+		}
+	}
+	return fmt.Sprintf(
+		"driver has exceeded working hours limit: %.2fh in 24h (limit %.2fh), %.2fh in 7 days (limit %.2fh)",
+		hos.DailyHours, hos.DailyLimitHours, hos.WeeklyHours, hos.WeeklyLimitHours), nil
+}
+
+// checkBookings ищет активные назначения водителя или техники на тикетах с пересекающимся окном.
+// Повторное назначение той же пары на тот же тикет запрещено всегда, остальные пересечения
+// допускаются только явно, с обоснованием. Назначение ignoreID (например, заменяемое) не учитывается.
+func (s *AssignmentService) checkBookings(ctx context.Context, ticket *model.Ticket, driverID, vehicleID, ignoreID uuid.UUID, allow bool, reason string) (*string, error) {
+	bookings, err := s.assignmentRepo.FindOverlappingBookings(ctx, []uuid.UUID{driverID}, []uuid.UUID{vehicleID}, ticket.PlannedStartAt, ticket.PlannedEndAt)
+	if err != nil {
 		return nil, err
 	}
 
-	return assignment, nil
+	conflict := BookingConflict{DriverID: driverID, VehicleID: vehicleID}
+	for _, b := range bookings {
+		if b.AssignmentID == ignoreID {
+			continue
+		}
+		if b.TicketID == ticket.ID && b.DriverID == driverID && b.VehicleID == vehicleID {
+			return nil, fmt.Errorf("%w: driver and vehicle are already assigned to this ticket", ErrConflict)
+		}
+		conflict.Overlapping = append(conflict.Overlapping, BookingOverlap{
+			TicketID:       b.TicketID,
+			AssignmentID:   b.AssignmentID,
+			TicketStatus:   b.TicketStatus,
+			PlannedStartAt: b.PlannedStartAt,
+			PlannedEndAt:   b.PlannedEndAt,
+			DriverBusy:     b.DriverID == driverID,
+			VehicleBusy:    b.VehicleID == vehicleID,
+		})
+	}
+
+	if len(conflict.Overlapping) == 0 {
+		return nil, nil
+	}
+
+	if !allow {
+		return nil, &ConflictError{
+			Message: "driver or vehicle is already booked in the planned window",
+			Details: conflict,
+		}
+	}
+
+	// Совмещение работ допускается только с обоснованием
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrInvalidInput
+	}
+	return &reason, nil
+}
+
+type SwapAssignmentInput struct {
+	DriverID  string
+	VehicleID string
+	// At - момент замены в RFC3339, по умолчанию текущее время
+	At            string
+	Reason        string
+	AllowOverlap  bool
+	OverlapReason string
 }
 
-func (s *AssignmentService) Delete(ctx context.Context, principal model.Principal, id string) error {
+// Swap меняет технику и/или водителя в активном назначении: закрывает прежнюю пару
+// в момент замены и открывает новую, не теряя привязку рейсов по обе стороны замены
+func (s *AssignmentService) Swap(ctx context.Context, principal model.Principal, id string, input SwapAssignmentInput, expectedVersion *int64) (*model.TicketAssignment, error) {
+	if !principal.IsContractor() {
+		return nil, ErrPermissionDenied
+	}
+
+	old, err := s.assignmentRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	ticket, err := s.ticketRepo.GetByID(ctx, old.TicketID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket.ContractorID != principal.OrgID {
+		return nil, ErrPermissionDenied
+	}
+
+	if err := checkVersion(expectedVersion, old.Version); err != nil {
+		return nil, err
+	}
+
+	driverID, vehicleID := old.DriverID, old.VehicleID
+	if input.DriverID != "" {
+		if driverID, err = uuid.Parse(input.DriverID); err != nil {
+			return nil, ErrInvalidInput
+		}
+	}
+	if input.VehicleID != "" {
+		if vehicleID, err = uuid.Parse(input.VehicleID); err != nil {
+			return nil, ErrInvalidInput
+		}
+	}
+	if driverID == old.DriverID && vehicleID == old.VehicleID {
+		return nil, fmt.Errorf("%w: new driver or vehicle is required", ErrInvalidInput)
+	}
+
+	now := time.Now()
+	at := now
+	if input.At != "" {
+		if at, err = time.Parse(time.RFC3339, input.At); err != nil {
+			return nil, ErrInvalidInput
+		}
+	}
+	if at.Before(old.AssignedAt) || at.After(now) {
+		return nil, fmt.Errorf("%w: swap time must be between assignment start and now", ErrInvalidInput)
+	}
+
+	if !old.IsActive {
+		return nil, fmt.Errorf("%w: assignment is not active", ErrConflict)
+	}
+	if old.DriverMarkStatus == model.DriverMarkStatusCompleted {
+		return nil, fmt.Errorf("%w: assignment is already completed", ErrConflict)
+	}
+	if ticket.Status == model.TicketStatusClosed || ticket.Status == model.TicketStatusCancelled {
+		return nil, fmt.Errorf("%w: ticket is %s", ErrConflict, ticket.Status)
+	}
+
+	overlapReason, err := s.checkBookings(ctx, ticket, driverID, vehicleID, old.ID, input.AllowOverlap, input.OverlapReason)
+	if err != nil {
+		return nil, err
+	}
+
+	var hoursWarning string
+	if driverID != old.DriverID {
+		if hoursWarning, err = s.checkDriverHours(ctx, driverID); err != nil {
+			return nil, err
+		}
+	}
+
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		reason = "swap"
+	}
+	old.UnassignedBy = &principal.UserID
+	old.UnassignReason = &reason
+
+	// Новая пара продолжает работу с того же этапа
+	replacement := &model.TicketAssignment{
+		TicketID:         old.TicketID,
+		DriverID:         driverID,
+		VehicleID:        vehicleID,
+		DriverMarkStatus: old.DriverMarkStatus,
+		AssignedAt:       at,
+		IsActive:         true,
+		OverlapReason:    overlapReason,
+		ReplacesID:       &old.ID,
+	}
+	if old.DriverMarkStatus == model.DriverMarkStatusInWork {
+		replacement.StartedAt = &at
+	}
+
+	if err := s.assignmentRepo.Swap(ctx, old, replacement, at, principal.UserID); err != nil {
+		return nil, mapUpdateError(err)
+	}
+
+	if hoursWarning != "" {
+		replacement.Warnings = append(replacement.Warnings, hoursWarning)
+	}
+
+	return replacement, nil
+}
+
+func (s *AssignmentService) Delete(ctx context.Context, principal model.Principal, id string, reason string, expectedVersion *int64) error {
 	// Только подрядчик может удалять назначения
 	if !principal.IsContractor() {
 		return ErrPermissionDenied
@@ -103,10 +361,29 @@ func (s *AssignmentService) Delete(ctx context.Context, principal model.Principa
 		return ErrPermissionDenied
 	}
 
-	return s.assignmentRepo.Delete(ctx, id)
+	if err := checkVersion(expectedVersion, assignment.Version); err != nil {
+		return err
+	}
+
+	if !assignment.IsActive {
+		return fmt.Errorf("%w: assignment is already inactive", ErrConflict)
+	}
+
+	assignment.UnassignedBy = &principal.UserID
+	if reason = strings.TrimSpace(reason); reason != "" {
+		assignment.UnassignReason = &reason
+	}
+
+	return mapUpdateError(s.assignmentRepo.Delete(ctx, assignment))
+}
+
+// MarkLocation - координаты водителя в момент отметки
+type MarkLocation struct {
+	Lat float64
+	Lon float64
 }
 
-func (s *AssignmentService) UpdateDriverMarkStatus(ctx context.Context, principal model.Principal, id string, status model.DriverMarkStatus) error {
+func (s *AssignmentService) UpdateDriverMarkStatus(ctx context.Context, principal model.Principal, id string, status model.DriverMarkStatus, location *MarkLocation, expectedVersion *int64) error {
 	// Только водитель может обновлять свой статус
 	if !principal.IsDriver() || principal.DriverID == nil {
 		return ErrPermissionDenied
@@ -125,26 +402,59 @@ func (s *AssignmentService) UpdateDriverMarkStatus(ctx context.Context, principa
 		return ErrPermissionDenied
 	}
 
-	// Обновляем статус
-	if err := s.assignmentRepo.UpdateDriverMarkStatus(ctx, id, status); err != nil {
+	if err := checkVersion(expectedVersion, assignment.Version); err != nil {
 		return err
 	}
 
-	// Если водитель отметил "В работе", проверяем, нужно ли перевести тикет в IN_PROGRESS
-	if status == model.DriverMarkStatusInWork {
-		ticket, err := s.ticketRepo.GetByID(ctx, assignment.TicketID.String())
-		if err != nil {
-			return err
-		}
+	if location != nil && (location.Lat < -90 || location.Lat > 90 || location.Lon < -180 || location.Lon > 180) {
+		return fmt.Errorf("%w: invalid coordinates", ErrInvalidInput)
+	}
 
-		if ticket.Status == model.TicketStatusPlanned && ticket.FactStartAt == nil {
-			now := time.Now()
-			ticket.Status = model.TicketStatusInProgress
-			ticket.FactStartAt = &now
-			if err := s.ticketRepo.Update(ctx, ticket); err != nil {
-				return err
-			}
+	if !assignment.IsActive {
+		return fmt.Errorf("%w: assignment is not active", ErrConflict)
+	}
+
+	if !assignment.DriverMarkStatus.CanTransitionTo(status) {
+		return fmt.Errorf("%w: cannot change mark status from %s to %s", ErrConflict, assignment.DriverMarkStatus, status)
+	}
+
+	ticket, err := s.ticketRepo.GetByID(ctx, assignment.TicketID.String())
+	if err != nil {
+		return err
+	}
+
+	// По закрытым и отмененным тикетам отметки не принимаются
+	if ticket.Status == model.TicketStatusClosed || ticket.Status == model.TicketStatusCancelled {
+		return fmt.Errorf("%w: ticket is %s", ErrConflict, ticket.Status)
+	}
+
+	now := time.Now()
+	assignment.DriverMarkStatus = status
+	switch status {
+	case model.DriverMarkStatusInWork:
+		assignment.StartedAt = &now
+		if location != nil {
+			assignment.StartedLat = &location.Lat
+			assignment.StartedLon = &location.Lon
 		}
+	case model.DriverMarkStatusCompleted:
+		assignment.CompletedAt = &now
+		if location != nil {
+			assignment.CompletedLat = &location.Lat
+			assignment.CompletedLon = &location.Lon
+		}
+	}
+
+	// Если водитель отметил "В работе", тикет переводится в IN_PROGRESS вместе с отметкой
+	var startedTicket *model.Ticket
+	if status == model.DriverMarkStatusInWork && ticket.Status == model.TicketStatusPlanned && ticket.FactStartAt == nil {
+		ticket.Status = model.TicketStatusInProgress
+		ticket.FactStartAt = &now
+		startedTicket = ticket
+	}
+
+	if err := s.assignmentRepo.UpdateDriverMarkStatus(ctx, assignment, startedTicket); err != nil {
+		return mapUpdateError(err)
 	}
 
 	return nil
@@ -194,3 +504,159 @@ func (s *AssignmentService) ListByTicketID(ctx context.Context, principal model.
 	return s.assignmentRepo.ListByTicketID(ctx, ticket.ID)
 }
 
+// AssignmentHistoryEntry - назначение (в том числе снятое) вместе с привязанными к нему рейсами
+type AssignmentHistoryEntry struct {
+	model.TicketAssignment
+	Trips       []model.Trip `json:"trips"`
+	TripsCount  int          `json:"trips_count"`
+	TotalVolume float64      `json:"total_volume"`
+}
+
+// History возвращает полную историю назначений тикета, включая снятые, с рейсами по каждому назначению
+func (s *AssignmentService) History(ctx context.Context, principal model.Principal, ticketID string) ([]AssignmentHistoryEntry, error) {
+	ticket, err := s.ticketRepo.GetByID(ctx, ticketID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if !canAccessTicket(principal, ticket) {
+		return nil, ErrPermissionDenied
+	}
+
+	assignments, err := s.assignmentRepo.ListHistoryByTicketID(ctx, ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Водитель видит только свои назначения
+	if principal.IsDriver() {
+		var own []model.TicketAssignment
+		for _, a := range assignments {
+			if a.DriverID == *principal.DriverID {
+				own = append(own, a)
+			}
+		}
+		assignments = own
+	}
+
+	ids := make([]uuid.UUID, 0, len(assignments))
+	for _, a := range assignments {
+		ids = append(ids, a.ID)
+	}
+
+	trips, err := s.tripRepo.ListByAssignmentIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	tripsByAssignment := make(map[uuid.UUID][]model.Trip)
+	for _, t := range trips {
+		tripsByAssignment[*t.TicketAssignmentID] = append(tripsByAssignment[*t.TicketAssignmentID], t)
+	}
+
+	history := make([]AssignmentHistoryEntry, 0, len(assignments))
+	for _, a := range assignments {
+		entry := AssignmentHistoryEntry{
+			TicketAssignment: a,
+			Trips:            tripsByAssignment[a.ID],
+		}
+		if entry.Trips == nil {
+			entry.Trips = []model.Trip{}
+		}
+		entry.TripsCount = len(entry.Trips)
+		for _, t := range entry.Trips {
+			if t.DetectedVolumeEntry != nil {
+				entry.TotalVolume += *t.DetectedVolumeEntry
+			}
+		}
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// DriverTicketSummary - краткие сведения о тикете для экрана водителя
+type DriverTicketSummary struct {
+	ID             uuid.UUID            `json:"id"`
+	CleaningAreaID uuid.UUID            `json:"cleaning_area_id"`
+	Status         model.TicketStatus   `json:"status"`
+	Priority       model.TicketPriority `json:"priority"`
+	PlannedStartAt time.Time            `json:"planned_start_at"`
+	PlannedEndAt   time.Time            `json:"planned_end_at"`
+	Description    string               `json:"description"`
+	Latitude       *float64             `json:"latitude"`
+	Longitude      *float64             `json:"longitude"`
+}
+
+// DriverAssignmentView - назначение водителя с тикетом и итогами рейсов за сегодня
+type DriverAssignmentView struct {
+	model.TicketAssignment
+	Ticket      DriverTicketSummary `json:"ticket"`
+	TodayTrips  int64               `json:"today_trips"`
+	TodayVolume float64             `json:"today_volume"`
+}
+
+// ListMine возвращает текущие и предстоящие назначения водителя. Назначения в работе идут первыми
+func (s *AssignmentService) ListMine(ctx context.Context, principal model.Principal) ([]DriverAssignmentView, error) {
+	if !principal.IsDriver() || principal.DriverID == nil {
+		return nil, ErrPermissionDenied
+	}
+
+	assignments, err := s.assignmentRepo.ListCurrentByDriverID(ctx, *principal.DriverID)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketIDs := make([]uuid.UUID, 0, len(assignments))
+	assignmentIDs := make([]uuid.UUID, 0, len(assignments))
+	for _, a := range assignments {
+		ticketIDs = append(ticketIDs, a.TicketID)
+		assignmentIDs = append(assignmentIDs, a.ID)
+	}
+
+	tickets, err := s.ticketRepo.GetByIDs(ctx, ticketIDs)
+	if err != nil {
+		return nil, err
+	}
+	ticketsByID := make(map[uuid.UUID]model.Ticket, len(tickets))
+	for _, t := range tickets {
+		ticketsByID[t.ID] = t
+	}
+
+	stats, err := s.tripRepo.GetStatsByAssignmentIDs(ctx, assignmentIDs, startOfDay(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]DriverAssignmentView, 0, len(assignments))
+	for _, a := range assignments {
+		t := ticketsByID[a.TicketID]
+		views = append(views, DriverAssignmentView{
+			TicketAssignment: a,
+			Ticket: DriverTicketSummary{
+				ID:             t.ID,
+				CleaningAreaID: t.CleaningAreaID,
+				Status:         t.Status,
+				Priority:       t.Priority,
+				PlannedStartAt: t.PlannedStartAt,
+				PlannedEndAt:   t.PlannedEndAt,
+				Description:    t.Description,
+				Latitude:       t.Latitude,
+				Longitude:      t.Longitude,
+			},
+			TodayTrips:  stats[a.ID].TripsCount,
+			TodayVolume: stats[a.ID].Volume,
+		})
+	}
+
+	// Назначения в работе показываем первыми, остальные - по плановому началу
+	sort.SliceStable(views, func(i, j int) bool {
+		return views[i].DriverMarkStatus == model.DriverMarkStatusInWork &&
+			views[j].DriverMarkStatus != model.DriverMarkStatusInWork
+	})
+
+	return views, nil
+}
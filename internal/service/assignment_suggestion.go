@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ticket-service/internal/model"
+)
+
+const (
+	// suggestionCrewPeriod - за какой период подрядчика учитываются его водители и техника
+	suggestionCrewPeriod = 90 * 24 * time.Hour
+	// suggestionStatsPeriod - за какой период считается доля нарушений и средний объем рейса
+	suggestionStatsPeriod = 30 * 24 * time.Hour
+)
+
+// DriverSuggestion - водитель подрядчика с показателями для подбора экипажа
+type DriverSuggestion struct {
+	DriverID      uuid.UUID   `json:"driver_id"`
+	Available     bool        `json:"available"`
+	BusyTicketIDs []uuid.UUID `json:"busy_ticket_ids,omitempty"`
+	DailyHours    float64     `json:"daily_hours"`
+	WeeklyHours   float64     `json:"weekly_hours"`
+	HoursExceeded bool        `json:"hours_exceeded"`
+	TripsCount    int64       `json:"trips_count"`
+	ViolationRate float64     `json:"violation_rate"`
+	Score         float64     `json:"score"`
+}
+
+// VehicleSuggestion - техника подрядчика с показателями для подбора экипажа.
+// Вместимость оценивается по среднему объему рейса
+type VehicleSuggestion struct {
+	VehicleID     uuid.UUID   `json:"vehicle_id"`
+	Available     bool        `json:"available"`
+	BusyTicketIDs []uuid.UUID `json:"busy_ticket_ids,omitempty"`
+	AvgVolume     float64     `json:"avg_volume"`
+	TripsCount    int64       `json:"trips_count"`
+	ViolationRate float64     `json:"violation_rate"`
+	Score         float64     `json:"score"`
+}
+
+// SuggestedPair - предлагаемая пара водитель + техника
+type SuggestedPair struct {
+	DriverID  uuid.UUID `json:"driver_id"`
+	VehicleID uuid.UUID `json:"vehicle_id"`
+	Score     float64   `json:"score"`
+}
+
+// AssignmentSuggestions - ранжированные водители, техника и предлагаемые пары для тикета
+type AssignmentSuggestions struct {
+	TicketID  uuid.UUID           `json:"ticket_id"`
+	Drivers   []DriverSuggestion  `json:"drivers"`
+	Vehicles  []VehicleSuggestion `json:"vehicles"`
+	Suggested []SuggestedPair     `json:"suggested"`
+}
+
+// Suggest ранжирует водителей и технику подрядчика для тикета.
+// Водитель: 100 баллов минус до 50 за долю нарушений и до 50 за загрузку относительно недельного лимита.
+// Техника: до 60 баллов за вместимость относительно лучшей техники и до 40 за отсутствие нарушений.
+// Занятые в плановом окне тикета и превысившие лимит часов в предлагаемые пары не попадают
+func (s *AssignmentService) Suggest(ctx context.Context, principal model.Principal, ticketID string, count int) (*AssignmentSuggestions, error) {
+	if !principal.IsContractor() {
+		return nil, ErrPermissionDenied
+	}
+
+	ticket, err := s.ticketRepo.GetByID(ctx, ticketID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if ticket.ContractorID != principal.OrgID {
+		return nil, ErrPermissionDenied
+	}
+
+	if count < 1 {
+		count = 1
+	}
+
+	now := time.Now()
+	driverIDs, vehicleIDs, err := s.assignmentRepo.ListContractorCrew(ctx, principal.OrgID, now.Add(-suggestionCrewPeriod))
+	if err != nil {
+		return nil, err
+	}
+
+	bookings, err := s.assignmentRepo.FindOverlappingBookings(ctx, driverIDs, vehicleIDs, ticket.PlannedStartAt, ticket.PlannedEndAt)
+	if err != nil {
+		return nil, err
+	}
+	busyDrivers := make(map[uuid.UUID][]uuid.UUID)
+	busyVehicles := make(map[uuid.UUID][]uuid.UUID)
+	for _, b := range bookings {
+		busyDrivers[b.DriverID] = append(busyDrivers[b.DriverID], b.TicketID)
+		busyVehicles[b.VehicleID] = append(busyVehicles[b.VehicleID], b.TicketID)
+	}
+
+	weekAgo := now.Add(-7 * 24 * time.Hour)
+	worked, err := s.workTime.intervals(ctx, driverIDs, nil, weekAgo, now)
+	if err != nil {
+		return nil, err
+	}
+
+	since := now.Add(-suggestionStatsPeriod)
+	driverStats, err := s.tripRepo.GetDriverStats(ctx, driverIDs, since)
+	if err != nil {
+		return nil, err
+	}
+	vehicleStats, err := s.tripRepo.GetVehicleStats(ctx, vehicleIDs, since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AssignmentSuggestions{
+		TicketID:  ticket.ID,
+		Drivers:   make([]DriverSuggestion, 0, len(driverIDs)),
+		Vehicles:  make([]VehicleSuggestion, 0, len(vehicleIDs)),
+		Suggested: []SuggestedPair{},
+	}
+
+	for _, driverID := range driverIDs {
+		stats := driverStats[driverID]
+		d := DriverSuggestion{
+			DriverID:      driverID,
+			BusyTicketIDs: busyDrivers[driverID],
+			DailyHours:    hours(sumIntervals(worked[driverID], now.Add(-24*time.Hour), now)),
+			WeeklyHours:   hours(sumIntervals(worked[driverID], weekAgo, now)),
+			TripsCount:    stats.TripsCount,
+			ViolationRate: violationRate(stats.TripsCount, stats.Violations),
+		}
+		d.Available = len(d.BusyTicketIDs) == 0
+		d.HoursExceeded = d.DailyHours > s.shiftCfg.DailyLimit.Hours() || d.WeeklyHours > s.shiftCfg.WeeklyLimit.Hours()
+
+		load := 0.0
+		if limit := s.shiftCfg.WeeklyLimit.Hours(); limit > 0 {
+			load = math.Min(d.WeeklyHours/limit, 1)
+		}
+		d.Score = round2(100 - 50*d.ViolationRate - 50*load)
+		result.Drivers = append(result.Drivers, d)
+	}
+
+	maxVolume := 0.0
+	for _, stats := range vehicleStats {
+		maxVolume = math.Max(maxVolume, stats.AvgVolume)
+	}
+	for _, vehicleID := range vehicleIDs {
+		stats := vehicleStats[vehicleID]
+		v := VehicleSuggestion{
+			VehicleID:     vehicleID,
+			BusyTicketIDs: busyVehicles[vehicleID],
+			AvgVolume:     round2(stats.AvgVolume),
+			TripsCount:    stats.TripsCount,
+			ViolationRate: violationRate(stats.TripsCount, stats.Violations),
+		}
+		v.Available = len(v.BusyTicketIDs) == 0
+
+		capacity := 0.0
+		if maxVolume > 0 {
+			capacity = stats.AvgVolume / maxVolume
+		}
+		v.Score = round2(60*capacity + 40*(1-v.ViolationRate))
+		result.Vehicles = append(result.Vehicles, v)
+	}
+
+	// Сначала доступные, затем по убыванию баллов
+	sort.SliceStable(result.Drivers, func(i, j int) bool {
+		a, b := result.Drivers[i], result.Drivers[j]
+		if a.Available != b.Available {
+			return a.Available
+		}
+		return a.Score > b.Score
+	})
+	sort.SliceStable(result.Vehicles, func(i, j int) bool {
+		a, b := result.Vehicles[i], result.Vehicles[j]
+		if a.Available != b.Available {
+			return a.Available
+		}
+		return a.Score > b.Score
+	})
+
+	var drivers []DriverSuggestion
+	for _, d := range result.Drivers {
+		if d.Available && !d.HoursExceeded {
+			drivers = append(drivers, d)
+		}
+	}
+	var vehicles []VehicleSuggestion
+	for _, v := range result.Vehicles {
+		if v.Available {
+			vehicles = append(vehicles, v)
+		}
+	}
+	for i := 0; i < count && i < len(drivers) && i < len(vehicles); i++ {
+		result.Suggested = append(result.Suggested, SuggestedPair{
+			DriverID:  drivers[i].DriverID,
+			VehicleID: vehicles[i].VehicleID,
+			Score:     round2((drivers[i].Score + vehicles[i].Score) / 2),
+		})
+	}
+
+	return result, nil
+}
+
+// AssignmentPairInput - пара водитель + техника для назначения
+type AssignmentPairInput struct {
+	DriverID  string
+	VehicleID string
+}
+
+// AcceptSuggestions создает назначения для выбранного подрядчиком набора предложенных пар
+// одной транзакцией: при ошибке хотя бы в одной паре не создается ни одно назначение
+func (s *AssignmentService) AcceptSuggestions(ctx context.Context, principal model.Principal, ticketID string, pairs []AssignmentPairInput) ([]model.TicketAssignment, error) {
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("%w: pairs are required", ErrInvalidInput)
+	}
+
+	items := make([]BulkAssignmentItem, 0, len(pairs))
+	for _, pair := range pairs {
+		items = append(items, BulkAssignmentItem{DriverID: pair.DriverID, VehicleID: pair.VehicleID})
+	}
+
+	result, err := s.BulkCreate(ctx, principal, ticketID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := make([]model.TicketAssignment, 0, len(result.Items))
+	for _, item := range result.Items {
+		assignments = append(assignments, *item.Assignment)
+	}
+	return assignments, nil
+}
+
+func violationRate(total, violations int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(violations)/float64(total)*1000) / 1000
+}
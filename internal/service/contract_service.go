@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ticket-service/internal/config"
+	"ticket-service/internal/model"
+	"ticket-service/internal/repository"
+)
+
+type ContractService struct {
+	contractRepo *repository.ContractRepository
+	cfg          config.ContractConfig
+}
+
+func NewContractService(contractRepo *repository.ContractRepository, cfg config.ContractConfig) *ContractService {
+	return &ContractService{
+		contractRepo: contractRepo,
+		cfg:          cfg,
+	}
+}
+
+type CreateContractInput struct {
+	Number       string
+	ContractorID string
+	StartsAt     string
+	EndsAt       string
+	MaxVolumeM3  float64
+	UnitTariff   float64
+}
+
+// ContractUsage - договор с нарастающим итогом по вывезенному объему и бюджету
+type ContractUsage struct {
+	Contract          *model.Contract `json:"contract"`
+	UsedVolumeM3      float64         `json:"used_volume_m3"`
+	RemainingVolumeM3 float64         `json:"remaining_volume_m3"`
+	UsagePercent      float64         `json:"usage_percent"`
+	UsedBudget        float64         `json:"used_budget"`
+	TotalBudget       float64         `json:"total_budget"`
+	ReachedThresholds []float64       `json:"reached_thresholds"`
+	Exhausted         bool            `json:"exhausted"`
+}
+
+func newContractUsage(contract *model.Contract, used float64, thresholds []float64) *ContractUsage {
+	usage := &ContractUsage{
+		Contract:          contract,
+		UsedVolumeM3:      used,
+		UsedBudget:        used * contract.UnitTariff,
+		TotalBudget:       contract.MaxVolumeM3 * contract.UnitTariff,
+		ReachedThresholds: []float64{},
+	}
+
+	if contract.MaxVolumeM3 > 0 {
+		usage.UsagePercent = used / contract.MaxVolumeM3 * 100
+	}
+	if remaining := contract.MaxVolumeM3 - used; remaining > 0 {
+		usage.RemainingVolumeM3 = remaining
+	}
+	usage.Exhausted = used >= contract.MaxVolumeM3
+
+	for _, threshold := range thresholds {
+		if usage.UsagePercent >= threshold {
+			usage.ReachedThresholds = append(usage.ReachedThresholds, threshold)
+		}
+	}
+
+	return usage
+}
+
+func (s *ContractService) Create(ctx context.Context, principal model.Principal, input CreateContractInput) (*model.Contract, error) {
+	// Договоры заводит KGU ZKH
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+
+	number := strings.TrimSpace(input.Number)
+	if number == "" {
+		return nil, ErrInvalidInput
+	}
+
+	contractorID, err := uuid.Parse(input.ContractorID)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	startsAt, err := time.Parse(time.RFC3339, input.StartsAt)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	endsAt, err := time.Parse(time.RFC3339, input.EndsAt)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	if !endsAt.After(startsAt) {
+		return nil, ErrInvalidInput
+	}
+
+	if input.MaxVolumeM3 <= 0 || input.UnitTariff < 0 {
+		return nil, ErrInvalidInput
+	}
+
+	contract := &model.Contract{
+		Number:         number,
+		ContractorID:   contractorID,
+		CreatedByOrgID: principal.OrgID,
+		StartsAt:       startsAt,
+		EndsAt:         endsAt,
+		MaxVolumeM3:    input.MaxVolumeM3,
+		UnitTariff:     input.UnitTariff,
+	}
+
+	if err := s.contractRepo.Create(ctx, contract); err != nil {
+		return nil, err
+	}
+
+	return contract, nil
+}
+
+func (s *ContractService) Get(ctx context.Context, principal model.Principal, id string) (*ContractUsage, error) {
+	contract, err := s.contractRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if !canAccessContract(principal, contract) {
+		return nil, ErrPermissionDenied
+	}
+
+	used, err := s.contractRepo.GetUsedVolumes(ctx, []uuid.UUID{contract.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return newContractUsage(contract, used[contract.ID], s.cfg.AlertThresholds), nil
+}
+
+// List возвращает договоры с итогами. alertsOnly оставляет только договоры,
+// достигшие хотя бы одного порога предупреждения.
+func (s *ContractService) List(ctx context.Context, principal model.Principal, filter repository.ContractListFilter, alertsOnly bool) ([]*ContractUsage, error) {
+	if principal.IsAkimat() {
+		// Акимат видит все
+	} else if principal.IsToo() {
+		orgID := principal.OrgID.String()
+		filter.CreatedByOrgID = &orgID
+	} else if principal.IsContractor() {
+		contractorID := principal.OrgID.String()
+		filter.ContractorID = &contractorID
+	} else {
+		return nil, ErrPermissionDenied
+	}
+
+	contracts, err := s.contractRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(contracts))
+	for _, c := range contracts {
+		ids = append(ids, c.ID)
+	}
+
+	used, err := s.contractRepo.GetUsedVolumes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ContractUsage, 0, len(contracts))
+	for i := range contracts {
+		usage := newContractUsage(&contracts[i], used[contracts[i].ID], s.cfg.AlertThresholds)
+		if alertsOnly && len(usage.ReachedThresholds) == 0 {
+			continue
+		}
+		result = append(result, usage)
+	}
+
+	return result, nil
+}
+
+func canAccessContract(principal model.Principal, contract *model.Contract) bool {
+	if principal.IsAkimat() {
+		return true
+	}
+	if principal.IsToo() {
+		return contract.CreatedByOrgID == principal.OrgID
+	}
+	if principal.IsContractor() {
+		return contract.ContractorID == principal.OrgID
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ticket-service/internal/model"
+	"ticket-service/internal/repository"
+)
+
+const maxContractorReportPeriod = 366 * 24 * time.Hour
+
+// ContractorReportInput - параметры отчета. Пустые значения не ограничивают отбор,
+// период по умолчанию - с начала текущего месяца
+type ContractorReportInput struct {
+	ContractorID   string
+	CleaningAreaID string
+	ContractID     string
+	From           string
+	To             string
+}
+
+// ContractorTicketReport - тикеты подрядчика за период
+type ContractorTicketReport struct {
+	Total              int64                        `json:"total"`
+	ByStatus           map[model.TicketStatus]int64 `json:"by_status"`
+	Completed          int64                        `json:"completed"`
+	CompletedOnTime    int64                        `json:"completed_on_time"`
+	OnTimePercent      float64                      `json:"on_time_percent"`
+	AvgCompletionHours float64                      `json:"avg_completion_hours"`
+}
+
+// TripViolationReport - рейсы с нарушением одного вида и их доля от всех рейсов
+type TripViolationReport struct {
+	Count   int64   `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// ContractorTripReport - рейсы подрядчика за период: объем и нарушения по статусам рейса
+type ContractorTripReport struct {
+	Total            int64                                    `json:"total"`
+	VolumeM3         float64                                  `json:"volume_m3"`
+	Violations       int64                                    `json:"violations"`
+	ViolationPercent float64                                  `json:"violation_percent"`
+	ByStatus         map[model.TripStatus]TripViolationReport `json:"by_status"`
+}
+
+// ContractorAppealReport - обжалования по тикетам подрядчика. Доля одобренных считается
+// от обжалований с вынесенным решением
+type ContractorAppealReport struct {
+	Total           int64   `json:"total"`
+	Approved        int64   `json:"approved"`
+	Rejected        int64   `json:"rejected"`
+	ApprovalPercent float64 `json:"approval_percent"`
+}
+
+// ContractorPerformance - показатели одного подрядчика за период отчета
+type ContractorPerformance struct {
+	ContractorID uuid.UUID              `json:"contractor_id"`
+	Tickets      ContractorTicketReport `json:"tickets"`
+	Trips        ContractorTripReport   `json:"trips"`
+	Appeals      ContractorAppealReport `json:"appeals"`
+}
+
+// ContractorReport - отчет по работе подрядчиков за период
+type ContractorReport struct {
+	From           time.Time               `json:"from"`
+	To             time.Time               `json:"to"`
+	CleaningAreaID *uuid.UUID              `json:"cleaning_area_id"`
+	ContractID     *uuid.UUID              `json:"contract_id"`
+	Contractors    []ContractorPerformance `json:"contractors"`
+}
+
+// ContractorReport строит отчет по подрядчикам: тикеты по статусам, выполнение в срок,
+// рейсы, объем, нарушения по статусам рейса и итог обжалований.
+// Акимат видит всех подрядчиков, КГУ - по своим тикетам, подрядчик - только себя
+func (s *TicketService) ContractorReport(ctx context.Context, principal model.Principal, input ContractorReportInput) (*ContractorReport, error) {
+	filter := repository.ContractorReportFilter{}
+
+	var err error
+	if filter.ContractorID, err = parseOptionalUUID(input.ContractorID, "contractor_id"); err != nil {
+		return nil, err
+	}
+	if filter.CleaningAreaID, err = parseOptionalUUID(input.CleaningAreaID, "cleaning_area_id"); err != nil {
+		return nil, err
+	}
+	if filter.ContractID, err = parseOptionalUUID(input.ContractID, "contract_id"); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case principal.IsAkimat():
+	case principal.IsToo():
+		orgID := principal.OrgID
+		filter.CreatedByOrgID = &orgID
+	case principal.IsContractor():
+		orgID := principal.OrgID
+		filter.ContractorID = &orgID
+	default:
+		return nil, ErrPermissionDenied
+	}
+
+	now := time.Now()
+	filter.To = now
+	if input.To != "" {
+		parsed, err := time.Parse(time.RFC3339, input.To)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid to", ErrInvalidInput)
+		}
+		filter.To = parsed
+	}
+	filter.From = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if input.From != "" {
+		parsed, err := time.Parse(time.RFC3339, input.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid from", ErrInvalidInput)
+		}
+		filter.From = parsed
+	}
+	if !filter.From.Before(filter.To) || filter.To.Sub(filter.From) > maxContractorReportPeriod {
+		return nil, fmt.Errorf("%w: invalid report period", ErrInvalidInput)
+	}
+
+	statusCounts, err := s.ticketRepo.CountTicketsByStatus(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	completion, err := s.ticketRepo.GetCompletionStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	tripStats, err := s.ticketRepo.GetTripStatsByStatus(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	appealStats, err := s.ticketRepo.GetAppealStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byContractor := make(map[uuid.UUID]*ContractorPerformance)
+	performance := func(contractorID uuid.UUID) *ContractorPerformance {
+		if p, ok := byContractor[contractorID]; ok {
+			return p
+		}
+		p := &ContractorPerformance{
+			ContractorID: contractorID,
+			Tickets:      ContractorTicketReport{ByStatus: map[model.TicketStatus]int64{}},
+			Trips:        ContractorTripReport{ByStatus: map[model.TripStatus]TripViolationReport{}},
+		}
+		byContractor[contractorID] = p
+		return p
+	}
+
+	for _, row := range statusCounts {
+		p := performance(row.ContractorID)
+		p.Tickets.ByStatus[row.Status] = row.Count
+		p.Tickets.Total += row.Count
+	}
+
+	for _, row := range completion {
+		p := performance(row.ContractorID)
+		p.Tickets.Completed = row.Completed
+		p.Tickets.CompletedOnTime = row.CompletedOnTime
+		p.Tickets.OnTimePercent = percent(row.CompletedOnTime, row.Completed)
+		p.Tickets.AvgCompletionHours = round2(row.AvgCompletionHours)
+	}
+
+	for _, row := range tripStats {
+		p := performance(row.ContractorID)
+		p.Trips.Total += row.TripsCount
+		p.Trips.VolumeM3 += row.Volume
+		if row.Status != model.TripStatusOK {
+			p.Trips.Violations += row.TripsCount
+			p.Trips.ByStatus[row.Status] = TripViolationReport{Count: row.TripsCount}
+		}
+	}
+
+	for _, row := range appealStats {
+		p := performance(row.ContractorID)
+		p.Appeals = ContractorAppealReport{
+			Total:           row.Total,
+			Approved:        row.Approved,
+			Rejected:        row.Rejected,
+			ApprovalPercent: percent(row.Approved, row.Approved+row.Rejected),
+		}
+	}
+
+	report := &ContractorReport{
+		From:           filter.From,
+		To:             filter.To,
+		CleaningAreaID: filter.CleaningAreaID,
+		ContractID:     filter.ContractID,
+		Contractors:    make([]ContractorPerformance, 0, len(byContractor)),
+	}
+	for _, p := range byContractor {
+		// Доли нарушений считаются после того, как известно общее число рейсов
+		for status, violation := range p.Trips.ByStatus {
+			violation.Percent = percent(violation.Count, p.Trips.Total)
+			p.Trips.ByStatus[status] = violation
+		}
+		p.Trips.VolumeM3 = round2(p.Trips.VolumeM3)
+		p.Trips.ViolationPercent = percent(p.Trips.Violations, p.Trips.Total)
+		report.Contractors = append(report.Contractors, *p)
+	}
+	sort.Slice(report.Contractors, func(i, j int) bool {
+		return report.Contractors[i].ContractorID.String() < report.Contractors[j].ContractorID.String()
+	})
+
+	return report, nil
+}
+
+// parseOptionalUUID разбирает необязательный идентификатор из параметров запроса
+func parseOptionalUUID(raw, name string) (*uuid.UUID, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s", ErrInvalidInput, name)
+	}
+	return &parsed, nil
+}
+
+// percent - доля part от total в процентах, с точностью до сотых
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round2(float64(part) * 100 / float64(total))
+}
@@ -0,0 +1,16 @@
+package service
+
+// ConflictError - конфликт с подробностями, которые нужно отдать клиенту
+// (например, список пересекающихся тикетов). Совместим с errors.Is(err, ErrConflict).
+type ConflictError struct {
+	Message string
+	Details interface{}
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
@@ -0,0 +1,504 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ticket-service/internal/config"
+	"ticket-service/internal/model"
+	"ticket-service/internal/repository"
+)
+
+// maxShiftReportPeriod ограничивает период отчета по сменам
+const maxShiftReportPeriod = 93 * 24 * time.Hour
+
+// shiftCrewPeriod - за какой период назначений водитель считается водителем подрядчика
+const shiftCrewPeriod = 90 * 24 * time.Hour
+
+type ShiftService struct {
+	shiftRepo      *repository.DriverShiftRepository
+	assignmentRepo *repository.AssignmentRepository
+	workTime       workTime
+	cfg            config.ShiftConfig
+}
+
+func NewShiftService(shiftRepo *repository.DriverShiftRepository, assignmentRepo *repository.AssignmentRepository, tripRepo *repository.TripRepository, cfg config.ShiftConfig) *ShiftService {
+	return &ShiftService{
+		shiftRepo:      shiftRepo,
+		assignmentRepo: assignmentRepo,
+		workTime:       newWorkTime(shiftRepo, assignmentRepo, tripRepo),
+		cfg:            cfg,
+	}
+}
+
+// Open открывает смену. Подрядчик открывает смену своему водителю - назначавшемуся
+// на его тикеты, водитель - себе (подрядчиком считается организация из токена водителя)
+func (s *ShiftService) Open(ctx context.Context, principal model.Principal, driverID string) (*model.DriverShift, error) {
+	var id uuid.UUID
+	switch {
+	case principal.IsContractor():
+		parsed, err := uuid.Parse(driverID)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		own, err := s.assignmentRepo.IsContractorDriver(ctx, principal.OrgID, parsed, time.Now().Add(-shiftCrewPeriod))
+		if err != nil {
+			return nil, err
+		}
+		if !own {
+			return nil, ErrPermissionDenied
+		}
+		id = parsed
+	case principal.IsDriver() && principal.DriverID != nil:
+		id = *principal.DriverID
+	default:
+		return nil, ErrPermissionDenied
+	}
+
+	open, err := s.shiftRepo.GetOpenByDriverID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return nil, fmt.Errorf("%w: driver already has an open shift", ErrConflict)
+	}
+
+	shift := &model.DriverShift{
+		DriverID:     id,
+		ContractorID: principal.OrgID,
+		StartedAt:    time.Now(),
+		OpenedBy:     principal.UserID,
+	}
+	if err := s.shiftRepo.Create(ctx, shift); err != nil {
+		// Смену этому водителю открыли параллельно (уникальный индекс uq_driver_shifts_open)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: driver already has an open shift", ErrConflict)
+		}
+		return nil, err
+	}
+	return shift, nil
+}
+
+func (s *ShiftService) Close(ctx context.Context, principal model.Principal, id string) (*model.DriverShift, error) {
+	shift, err := s.shiftRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if !canAccessShift(principal, shift) {
+		return nil, ErrPermissionDenied
+	}
+
+	if !shift.IsOpen() {
+		return nil, fmt.Errorf("%w: shift is already closed", ErrConflict)
+	}
+
+	now := time.Now()
+	shift.EndedAt = &now
+	shift.ClosedBy = &principal.UserID
+	if err := s.shiftRepo.Close(ctx, shift); err != nil {
+		return nil, mapUpdateError(err)
+	}
+	return shift, nil
+}
+
+type ListShiftsInput struct {
+	ContractorID string
+	DriverID     string
+	From         string
+	To           string
+}
+
+func (s *ShiftService) List(ctx context.Context, principal model.Principal, input ListShiftsInput) ([]model.DriverShift, error) {
+	filter := repository.DriverShiftListFilter{}
+
+	switch {
+	case principal.IsAkimat():
+		if input.ContractorID != "" {
+			contractorID, err := uuid.Parse(input.ContractorID)
+			if err != nil {
+				return nil, ErrInvalidInput
+			}
+			filter.ContractorID = &contractorID
+		}
+	case principal.IsContractor():
+		filter.ContractorID = &principal.OrgID
+	case principal.IsDriver() && principal.DriverID != nil:
+		filter.DriverIDs = []uuid.UUID{*principal.DriverID}
+	default:
+		return nil, ErrPermissionDenied
+	}
+
+	if input.DriverID != "" && !principal.IsDriver() {
+		driverID, err := uuid.Parse(input.DriverID)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		filter.DriverIDs = []uuid.UUID{driverID}
+	}
+
+	if input.From != "" {
+		from, err := time.Parse(time.RFC3339, input.From)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		filter.From = &from
+	}
+	if input.To != "" {
+		to, err := time.Parse(time.RFC3339, input.To)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		filter.To = &to
+	}
+
+	return s.shiftRepo.List(ctx, filter)
+}
+
+// DriverDayHours - отработанные часы водителя за календарный день
+type DriverDayHours struct {
+	Date        string  `json:"date"`
+	WorkedHours float64 `json:"worked_hours"`
+	Exceeded    bool    `json:"exceeded"`
+}
+
+// DriverWeekHours - отработанные часы водителя за календарную неделю (с понедельника)
+type DriverWeekHours struct {
+	WeekStart   string  `json:"week_start"`
+	WorkedHours float64 `json:"worked_hours"`
+	Exceeded    bool    `json:"exceeded"`
+}
+
+// DriverShiftReport - сводка по одному водителю за период отчета
+type DriverShiftReport struct {
+	DriverID    uuid.UUID         `json:"driver_id"`
+	ShiftsCount int               `json:"shifts_count"`
+	ShiftHours  float64           `json:"shift_hours"`
+	WorkedHours float64           `json:"worked_hours"`
+	Days        []DriverDayHours  `json:"days"`
+	Weeks       []DriverWeekHours `json:"weeks"`
+	Exceeded    bool              `json:"exceeded"`
+}
+
+// ShiftReport - отчет по сменам и рабочему времени водителей подрядчика
+type ShiftReport struct {
+	ContractorID     uuid.UUID           `json:"contractor_id"`
+	From             time.Time           `json:"from"`
+	To               time.Time           `json:"to"`
+	DailyLimitHours  float64             `json:"daily_limit_hours"`
+	WeeklyLimitHours float64             `json:"weekly_limit_hours"`
+	Drivers          []DriverShiftReport `json:"drivers"`
+}
+
+// Report строит отчет по сменам подрядчика. Отработанное время складывается из смен,
+// периодов между отметками водителя и рейсов; пересечения не учитываются дважды.
+// По умолчанию - последние 7 дней
+func (s *ShiftService) Report(ctx context.Context, principal model.Principal, contractorID, fromRaw, toRaw string) (*ShiftReport, error) {
+	var contractor uuid.UUID
+	switch {
+	case principal.IsContractor():
+		contractor = principal.OrgID
+	case principal.IsAkimat():
+		parsed, err := uuid.Parse(contractorID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: contractor_id is required", ErrInvalidInput)
+		}
+		contractor = parsed
+	default:
+		return nil, ErrPermissionDenied
+	}
+
+	to := time.Now()
+	if toRaw != "" {
+		parsed, err := time.Parse(time.RFC3339, toRaw)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		to = parsed
+	}
+	from := to.Add(-7 * 24 * time.Hour)
+	if fromRaw != "" {
+		parsed, err := time.Parse(time.RFC3339, fromRaw)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		from = parsed
+	}
+	if !from.Before(to) || to.Sub(from) > maxShiftReportPeriod {
+		return nil, fmt.Errorf("%w: invalid report period", ErrInvalidInput)
+	}
+
+	shifts, err := s.shiftRepo.List(ctx, repository.DriverShiftListFilter{ContractorID: &contractor, From: &from, To: &to})
+	if err != nil {
+		return nil, err
+	}
+
+	worked, err := s.workTime.intervals(ctx, nil, &contractor, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	byDriver := make(map[uuid.UUID]*DriverShiftReport)
+	driverReport := func(driverID uuid.UUID) *DriverShiftReport {
+		if r, ok := byDriver[driverID]; ok {
+			return r
+		}
+		r := &DriverShiftReport{DriverID: driverID, Days: []DriverDayHours{}, Weeks: []DriverWeekHours{}}
+		byDriver[driverID] = r
+		return r
+	}
+
+	for _, shift := range shifts {
+		r := driverReport(shift.DriverID)
+		r.ShiftsCount++
+		end := now
+		if shift.EndedAt != nil {
+			end = *shift.EndedAt
+		}
+		r.ShiftHours += hours(clip(workInterval{start: shift.StartedAt, end: end}, from, to))
+	}
+
+	for driverID, intervals := range worked {
+		r := driverReport(driverID)
+		r.WorkedHours = hours(sumIntervals(intervals, from, to))
+
+		for day := startOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+			dayHours := hours(sumIntervals(intervals, day, day.AddDate(0, 0, 1)))
+			if dayHours == 0 {
+				continue
+			}
+			exceeded := dayHours > s.cfg.DailyLimit.Hours()
+			r.Days = append(r.Days, DriverDayHours{Date: day.Format("2006-01-02"), WorkedHours: dayHours, Exceeded: exceeded})
+			r.Exceeded = r.Exceeded || exceeded
+		}
+
+		for week := startOfWeek(from); week.Before(to); week = week.AddDate(0, 0, 7) {
+			weekHours := hours(sumIntervals(intervals, week, week.AddDate(0, 0, 7)))
+			if weekHours == 0 {
+				continue
+			}
+			exceeded := weekHours > s.cfg.WeeklyLimit.Hours()
+			r.Weeks = append(r.Weeks, DriverWeekHours{WeekStart: week.Format("2006-01-02"), WorkedHours: weekHours, Exceeded: exceeded})
+			r.Exceeded = r.Exceeded || exceeded
+		}
+	}
+
+	report := &ShiftReport{
+		ContractorID:     contractor,
+		From:             from,
+		To:               to,
+		DailyLimitHours:  s.cfg.DailyLimit.Hours(),
+		WeeklyLimitHours: s.cfg.WeeklyLimit.Hours(),
+		Drivers:          make([]DriverShiftReport, 0, len(byDriver)),
+	}
+	for _, r := range byDriver {
+		r.ShiftHours = round2(r.ShiftHours)
+		report.Drivers = append(report.Drivers, *r)
+	}
+	sort.Slice(report.Drivers, func(i, j int) bool {
+		return report.Drivers[i].WorkedHours > report.Drivers[j].WorkedHours
+	})
+
+	return report, nil
+}
+
+func canAccessShift(principal model.Principal, shift *model.DriverShift) bool {
+	if principal.IsContractor() {
+		return shift.ContractorID == principal.OrgID
+	}
+	if principal.IsDriver() && principal.DriverID != nil {
+		return shift.DriverID == *principal.DriverID
+	}
+	return false
+}
+
+// HoursOfService - отработанное водителем время в скользящих окнах 24 часа и 7 дней
+type HoursOfService struct {
+	DriverID         uuid.UUID `json:"driver_id"`
+	DailyHours       float64   `json:"daily_hours"`
+	WeeklyHours      float64   `json:"weekly_hours"`
+	DailyLimitHours  float64   `json:"daily_limit_hours"`
+	WeeklyLimitHours float64   `json:"weekly_limit_hours"`
+}
+
+// Exceeded сообщает, превышен ли хотя бы один из лимитов
+func (h HoursOfService) Exceeded() bool {
+	return h.DailyHours > h.DailyLimitHours || h.WeeklyHours > h.WeeklyLimitHours
+}
+
+type workInterval struct {
+	start time.Time
+	end   time.Time
+}
+
+// workTime собирает интервалы фактической работы водителей из смен, отметок и рейсов
+type workTime struct {
+	shiftRepo      *repository.DriverShiftRepository
+	assignmentRepo *repository.AssignmentRepository
+	tripRepo       *repository.TripRepository
+}
+
+func newWorkTime(shiftRepo *repository.DriverShiftRepository, assignmentRepo *repository.AssignmentRepository, tripRepo *repository.TripRepository) workTime {
+	return workTime{
+		shiftRepo:      shiftRepo,
+		assignmentRepo: assignmentRepo,
+		tripRepo:       tripRepo,
+	}
+}
+
+// hoursOfService считает отработанное водителем время за последние 24 часа и 7 дней
+func (w workTime) hoursOfService(ctx context.Context, driverID uuid.UUID, cfg config.ShiftConfig) (*HoursOfService, error) {
+	now := time.Now()
+	weekAgo := now.Add(-7 * 24 * time.Hour)
+
+	worked, err := w.intervals(ctx, []uuid.UUID{driverID}, nil, weekAgo, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HoursOfService{
+		DriverID:         driverID,
+		DailyHours:       hours(sumIntervals(worked[driverID], now.Add(-24*time.Hour), now)),
+		WeeklyHours:      hours(sumIntervals(worked[driverID], weekAgo, now)),
+		DailyLimitHours:  cfg.DailyLimit.Hours(),
+		WeeklyLimitHours: cfg.WeeklyLimit.Hours(),
+	}, nil
+}
+
+// intervals возвращает объединенные интервалы работы водителей в [from, to).
+// Водители берутся из driverIDs, а если задан contractorID - из смен и назначений подрядчика
+func (w workTime) intervals(ctx context.Context, driverIDs []uuid.UUID, contractorID *uuid.UUID, from, to time.Time) (map[uuid.UUID][]workInterval, error) {
+	now := time.Now()
+	raw := make(map[uuid.UUID][]workInterval)
+
+	shifts, err := w.shiftRepo.List(ctx, repository.DriverShiftListFilter{
+		ContractorID: contractorID,
+		DriverIDs:    driverIDs,
+		From:         &from,
+		To:           &to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, shift := range shifts {
+		end := now
+		if shift.EndedAt != nil {
+			end = *shift.EndedAt
+		}
+		raw[shift.DriverID] = append(raw[shift.DriverID], workInterval{start: shift.StartedAt, end: end})
+	}
+
+	assignments, err := w.assignmentRepo.ListMarkedBetween(ctx, driverIDs, contractorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assignments {
+		end := now
+		switch {
+		case a.CompletedAt != nil:
+			end = *a.CompletedAt
+		case a.UnassignedAt != nil:
+			end = *a.UnassignedAt
+		}
+		raw[a.DriverID] = append(raw[a.DriverID], workInterval{start: *a.StartedAt, end: end})
+	}
+
+	ids := driverIDs
+	if len(ids) == 0 {
+		for driverID := range raw {
+			ids = append(ids, driverID)
+		}
+	}
+	trips, err := w.tripRepo.ListByDriverIDsBetween(ctx, ids, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range trips {
+		if t.DriverID == nil || t.ExitAt == nil {
+			continue
+		}
+		raw[*t.DriverID] = append(raw[*t.DriverID], workInterval{start: t.EntryAt, end: *t.ExitAt})
+	}
+
+	merged := make(map[uuid.UUID][]workInterval, len(raw))
+	for driverID, intervals := range raw {
+		merged[driverID] = mergeIntervals(intervals)
+	}
+	return merged, nil
+}
+
+// mergeIntervals объединяет пересекающиеся интервалы
+func mergeIntervals(intervals []workInterval) []workInterval {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	var merged []workInterval
+	for _, in := range intervals {
+		if !in.end.After(in.start) {
+			continue
+		}
+		if n := len(merged); n > 0 && !in.start.After(merged[n-1].end) {
+			if in.end.After(merged[n-1].end) {
+				merged[n-1].end = in.end
+			}
+			continue
+		}
+		merged = append(merged, in)
+	}
+	return merged
+}
+
+// sumIntervals суммирует длительность объединенных интервалов внутри [from, to)
+func sumIntervals(intervals []workInterval, from, to time.Time) time.Duration {
+	var total time.Duration
+	for _, in := range intervals {
+		total += clip(in, from, to)
+	}
+	return total
+}
+
+func clip(in workInterval, from, to time.Time) time.Duration {
+	start, end := in.start, in.end
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+func hours(d time.Duration) float64 {
+	return round2(d.Hours())
+}
+
+// round2 округляет до сотых
+func round2(h float64) float64 {
+	return math.Round(h*100) / 100
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	offset := (int(day.Weekday()) + 6) % 7 // понедельник - начало недели
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"ticket-service/internal/config"
+	"ticket-service/internal/model"
+	"ticket-service/internal/repository"
+)
+
+type TicketCommentService struct {
+	commentRepo *repository.TicketCommentRepository
+	ticketRepo  *repository.TicketRepository
+	cfg         config.TicketConfig
+}
+
+func NewTicketCommentService(
+	commentRepo *repository.TicketCommentRepository,
+	ticketRepo *repository.TicketRepository,
+	cfg config.TicketConfig,
+) *TicketCommentService {
+	return &TicketCommentService{
+		commentRepo: commentRepo,
+		ticketRepo:  ticketRepo,
+		cfg:         cfg,
+	}
+}
+
+// TicketCommentView - комментарий с отметками о прочтении
+type TicketCommentView struct {
+	model.TicketComment
+	ReadBy []uuid.UUID `json:"read_by"`
+}
+
+type CreateTicketCommentInput struct {
+	Content          string
+	MentionedUserIDs []string
+}
+
+func (s *TicketCommentService) Create(ctx context.Context, principal model.Principal, ticketID string, input CreateTicketCommentInput) (*model.TicketComment, error) {
+	ticket, err := s.getTicket(ctx, principal, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(input.Content)
+	if content == "" {
+		return nil, ErrInvalidInput
+	}
+
+	comment := &model.TicketComment{
+		TicketID:        ticket.ID,
+		CreatedByUserID: principal.UserID,
+		AuthorRole:      principal.Role,
+		Content:         content,
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(input.MentionedUserIDs))
+	for _, raw := range input.MentionedUserIDs {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		comment.Mentions = append(comment.Mentions, model.TicketCommentMention{UserID: userID})
+	}
+
+	if err := s.commentRepo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	// Свое сообщение автор уже прочитал
+	if err := s.commentRepo.MarkRead(ctx, ticket.ID, principal.UserID, comment.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *TicketCommentService) List(ctx context.Context, principal model.Principal, ticketID string) ([]TicketCommentView, error) {
+	ticket, err := s.getTicket(ctx, principal, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := s.commentRepo.ListByTicketID(ctx, ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	reads, err := s.commentRepo.ListReads(ctx, ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]TicketCommentView, 0, len(comments))
+	for _, comment := range comments {
+		// Текст удаленного сообщения не отдаем
+		if comment.DeletedAt != nil {
+			comment.Content = ""
+			comment.Mentions = nil
+		}
+
+		view := TicketCommentView{TicketComment: comment, ReadBy: []uuid.UUID{}}
+		for _, read := range reads {
+			if read.UserID != comment.CreatedByUserID && !read.LastReadAt.Before(comment.CreatedAt) {
+				view.ReadBy = append(view.ReadBy, read.UserID)
+			}
+		}
+		result = append(result, view)
+	}
+
+	return result, nil
+}
+
+func (s *TicketCommentService) Update(ctx context.Context, principal model.Principal, ticketID, commentID, content string) (*model.TicketComment, error) {
+	comment, err := s.getEditableComment(ctx, principal, ticketID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrInvalidInput
+	}
+
+	now := time.Now()
+	if err := s.commentRepo.UpdateContent(ctx, comment.ID, content, now); err != nil {
+		return nil, err
+	}
+
+	comment.Content = content
+	comment.EditedAt = &now
+	return comment, nil
+}
+
+func (s *TicketCommentService) Delete(ctx context.Context, principal model.Principal, ticketID, commentID string) error {
+	comment, err := s.getEditableComment(ctx, principal, ticketID, commentID)
+	if err != nil {
+		return err
+	}
+
+	return s.commentRepo.Delete(ctx, comment.ID, time.Now())
+}
+
+// MarkRead отмечает все текущие сообщения обсуждения прочитанными
+func (s *TicketCommentService) MarkRead(ctx context.Context, principal model.Principal, ticketID string) error {
+	ticket, err := s.getTicket(ctx, principal, ticketID)
+	if err != nil {
+		return err
+	}
+
+	return s.commentRepo.MarkRead(ctx, ticket.ID, principal.UserID, time.Now())
+}
+
+// getTicket загружает тикет и проверяет доступ к обсуждению.
+// Обсуждение ведут КГУ, подрядчик и Акимат, водители в нем не участвуют.
+func (s *TicketCommentService) getTicket(ctx context.Context, principal model.Principal, ticketID string) (*model.Ticket, error) {
+	if principal.IsDriver() {
+		return nil, ErrPermissionDenied
+	}
+
+	ticket, err := s.ticketRepo.GetByID(ctx, ticketID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if !canAccessTicket(principal, ticket) {
+		return nil, ErrPermissionDenied
+	}
+
+	return ticket, nil
+}
+
+// getEditableComment проверяет, что комментарий принадлежит пользователю
+// и окно редактирования еще не закрыто
+func (s *TicketCommentService) getEditableComment(ctx context.Context, principal model.Principal, ticketID, commentID string) (*model.TicketComment, error) {
+	ticket, err := s.getTicket(ctx, principal, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.commentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if comment.TicketID != ticket.ID {
+		return nil, ErrNotFound
+	}
+
+	if comment.CreatedByUserID != principal.UserID {
+		return nil, ErrPermissionDenied
+	}
+
+	if comment.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: comment is deleted", ErrConflict)
+	}
+
+	if time.Since(comment.CreatedAt) > s.cfg.CommentEditWindow {
+		return nil, fmt.Errorf("%w: edit window has expired", ErrConflict)
+	}
+
+	return comment, nil
+}
@@ -3,11 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"ticket-service/internal/config"
 	"ticket-service/internal/model"
 	"ticket-service/internal/repository"
 )
@@ -17,13 +20,34 @@ var (
 	ErrPermissionDenied = errors.New("permission denied")
 	ErrInvalidInput     = errors.New("invalid input")
 	ErrConflict         = errors.New("conflict")
+	// ErrPreconditionFailed - версия из If-Match не совпадает с текущей версией записи
+	ErrPreconditionFailed = errors.New("precondition failed")
 )
 
+// checkVersion сравнивает ожидаемую клиентом версию (If-Match) с текущей
+func checkVersion(expected *int64, actual int64) error {
+	if expected != nil && *expected != actual {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// mapUpdateError переводит конфликт версий репозитория в ErrConflict
+func mapUpdateError(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return ErrConflict
+	}
+	return err
+}
+
 type TicketService struct {
 	ticketRepo     *repository.TicketRepository
 	tripRepo       *repository.TripRepository
 	assignmentRepo *repository.AssignmentRepository
 	appealRepo     *repository.AppealRepository
+	contractRepo   *repository.ContractRepository
+	commentRepo    *repository.TicketCommentRepository
+	cfg            config.TicketConfig
 }
 
 func NewTicketService(
@@ -31,12 +55,18 @@ func NewTicketService(
 	tripRepo *repository.TripRepository,
 	assignmentRepo *repository.AssignmentRepository,
 	appealRepo *repository.AppealRepository,
+	contractRepo *repository.ContractRepository,
+	commentRepo *repository.TicketCommentRepository,
+	cfg config.TicketConfig,
 ) *TicketService {
 	return &TicketService{
 		ticketRepo:     ticketRepo,
 		tripRepo:       tripRepo,
 		assignmentRepo: assignmentRepo,
 		appealRepo:     appealRepo,
+		contractRepo:   contractRepo,
+		commentRepo:    commentRepo,
+		cfg:            cfg,
 	}
 }
 
@@ -81,18 +111,43 @@ func (s *TicketService) Create(ctx context.Context, principal model.Principal, i
 		return nil, ErrInvalidInput
 	}
 
+	priority, err := parsePriority(input.Priority)
+	if err != nil {
+		return nil, err
+	}
+
 	ticket := &model.Ticket{
 		CleaningAreaID: cleaningAreaID,
 		ContractorID:   contractorID,
 		ContractID:     contractID, // *uuid.UUID
 		CreatedByOrgID: principal.OrgID,
 		Status:         model.TicketStatusPlanned,
+		Priority:       priority,
 		PlannedStartAt: plannedStartAt,
 		PlannedEndAt:   plannedEndAt,
 		Description:    input.Description,
 	}
+	ticket.SLADeadlineAt = s.slaDeadline(ticket)
+	s.requestAcceptance(ticket)
+
+	err = s.ticketRepo.Transaction(ctx, func(tickets *repository.TicketRepository, contracts *repository.ContractRepository) error {
+		if err := validateContract(ctx, contracts, principal, *contractID, contractorID, plannedStartAt, plannedEndAt); err != nil {
+			return err
+		}
+
+		// Проверяем, что участок не занят другим тикетом в это же время
+		if err := tickets.LockAreas(ctx, []uuid.UUID{cleaningAreaID}); err != nil {
+			return err
+		}
+		overlapReason, err := checkAreaOverlaps(ctx, tickets, cleaningAreaID, plannedStartAt, plannedEndAt, input.AllowOverlap, input.OverlapReason)
+		if err != nil {
+			return err
+		}
+		ticket.OverlapReason = overlapReason
 
-	if err := s.ticketRepo.Create(ctx, ticket); err != nil {
+		return tickets.Create(ctx, ticket)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -106,6 +161,459 @@ type CreateTicketInput struct {
 	PlannedStartAt string
 	PlannedEndAt   string
 	Description    string
+	Priority       string
+	// AllowOverlap разрешает создать тикет поверх пересекающегося окна на том же участке
+	AllowOverlap  bool
+	OverlapReason string
+}
+
+// TicketOverlap описывает тикет, плановое окно которого пересекается с новым
+type TicketOverlap struct {
+	TicketID       uuid.UUID          `json:"ticket_id"`
+	ContractorID   uuid.UUID          `json:"contractor_id"`
+	Status         model.TicketStatus `json:"status"`
+	PlannedStartAt time.Time          `json:"planned_start_at"`
+	PlannedEndAt   time.Time          `json:"planned_end_at"`
+}
+
+// AreaOverlapConflict - детали конфликта по участку уборки
+type AreaOverlapConflict struct {
+	CleaningAreaID     uuid.UUID       `json:"cleaning_area_id"`
+	OverlappingTickets []TicketOverlap `json:"overlapping_tickets"`
+}
+
+// validateContract проверяет, что договор заведен этим КГУ, оформлен на подрядчика тикета,
+// покрывает плановое окно и его лимит объема еще не исчерпан. Вызывается в транзакции
+// создания тикета: строка договора блокируется до ее завершения
+func validateContract(ctx context.Context, contracts *repository.ContractRepository, principal model.Principal, contractID, contractorID uuid.UUID, start, end time.Time) error {
+	contract, err := contracts.GetByIDForUpdate(ctx, contractID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: contract not found", ErrInvalidInput)
+		}
+		return err
+	}
+
+	if contract.CreatedByOrgID != principal.OrgID {
+		return ErrPermissionDenied
+	}
+
+	if contract.ContractorID != contractorID {
+		return fmt.Errorf("%w: contract belongs to another contractor", ErrInvalidInput)
+	}
+
+	if !contract.Covers(start, end) {
+		return fmt.Errorf("%w: planned window is outside of the contract period", ErrInvalidInput)
+	}
+
+	used, err := contracts.GetUsedVolumes(ctx, []uuid.UUID{contract.ID})
+	if err != nil {
+		return err
+	}
+
+	usage := newContractUsage(contract, used[contract.ID], nil)
+	if usage.Exhausted {
+		return &ConflictError{
+			Message: "contract volume limit is exhausted",
+			Details: usage,
+		}
+	}
+
+	return nil
+}
+
+// checkAreaOverlaps ищет неотмененные тикеты того же участка с пересекающимся окном.
+// При явном разрешении возвращает обязательное обоснование для сохранения в тикете.
+// Вызывается в транзакции создания после блокировки участка
+func checkAreaOverlaps(ctx context.Context, tickets *repository.TicketRepository, cleaningAreaID uuid.UUID, start, end time.Time, allow bool, reason string) (*string, error) {
+	overlapping, err := tickets.FindOverlapping(ctx, cleaningAreaID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(overlapping) == 0 {
+		return nil, nil
+	}
+
+	if !allow {
+		conflict := AreaOverlapConflict{CleaningAreaID: cleaningAreaID}
+		for _, t := range overlapping {
+			conflict.OverlappingTickets = append(conflict.OverlappingTickets, TicketOverlap{
+				TicketID:       t.ID,
+				ContractorID:   t.ContractorID,
+				Status:         t.Status,
+				PlannedStartAt: t.PlannedStartAt,
+				PlannedEndAt:   t.PlannedEndAt,
+			})
+		}
+		return nil, &ConflictError{
+			Message: "cleaning area already has a ticket in the planned window",
+			Details: conflict,
+		}
+	}
+
+	// Параллельные работы допускаются только с обоснованием
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrInvalidInput
+	}
+	return &reason, nil
+}
+
+// EmergencyAreaInput описывает один участок в режиме экстренного снегопада
+type EmergencyAreaInput struct {
+	CleaningAreaID string
+	ContractorID   string
+	ContractID     string
+}
+
+type CreateEmergencyTicketsInput struct {
+	Areas          []EmergencyAreaInput
+	PlannedStartAt string
+	PlannedEndAt   string
+	Description    string
+	AllowOverlap   bool
+	OverlapReason  string
+}
+
+// CreateEmergency включает режим экстренного снегопада: массово создает тикеты
+// с приоритетом EMERGENCY по списку участков. Если плановое окно не задано,
+// работы начинаются сразу и длятся в пределах экстренного SLA.
+func (s *TicketService) CreateEmergency(ctx context.Context, principal model.Principal, input CreateEmergencyTicketsInput) ([]*model.Ticket, error) {
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+
+	if len(input.Areas) == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	plannedStartAt := time.Now()
+	if input.PlannedStartAt != "" {
+		parsed, err := time.Parse(time.RFC3339, input.PlannedStartAt)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		plannedStartAt = parsed
+	}
+
+	plannedEndAt := plannedStartAt.Add(s.cfg.EmergencySLA)
+	if input.PlannedEndAt != "" {
+		parsed, err := time.Parse(time.RFC3339, input.PlannedEndAt)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		plannedEndAt = parsed
+	}
+
+	if !plannedEndAt.After(plannedStartAt) {
+		return nil, ErrInvalidInput
+	}
+
+	seenAreas := make(map[uuid.UUID]struct{}, len(input.Areas))
+	tickets := make([]*model.Ticket, 0, len(input.Areas))
+	for _, area := range input.Areas {
+		cleaningAreaID, err := uuid.Parse(area.CleaningAreaID)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+		if _, ok := seenAreas[cleaningAreaID]; ok {
+			return nil, ErrInvalidInput
+		}
+		seenAreas[cleaningAreaID] = struct{}{}
+
+		contractorID, err := uuid.Parse(area.ContractorID)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+
+		contractID, err := uuid.Parse(area.ContractID)
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+
+		ticket := &model.Ticket{
+			CleaningAreaID: cleaningAreaID,
+			ContractorID:   contractorID,
+			ContractID:     &contractID,
+			CreatedByOrgID: principal.OrgID,
+			Status:         model.TicketStatusPlanned,
+			Priority:       model.TicketPriorityEmergency,
+			PlannedStartAt: plannedStartAt,
+			PlannedEndAt:   plannedEndAt,
+			Description:    input.Description,
+		}
+		ticket.SLADeadlineAt = s.slaDeadline(ticket)
+		s.requestAcceptance(ticket)
+		tickets = append(tickets, ticket)
+	}
+
+	areaIDs := make([]uuid.UUID, 0, len(tickets))
+	for _, ticket := range tickets {
+		areaIDs = append(areaIDs, ticket.CleaningAreaID)
+	}
+
+	err := s.ticketRepo.Transaction(ctx, func(txTickets *repository.TicketRepository, contracts *repository.ContractRepository) error {
+		if err := txTickets.LockAreas(ctx, areaIDs); err != nil {
+			return err
+		}
+		for _, ticket := range tickets {
+			if err := validateContract(ctx, contracts, principal, *ticket.ContractID, ticket.ContractorID, plannedStartAt, plannedEndAt); err != nil {
+				return err
+			}
+			overlapReason, err := checkAreaOverlaps(ctx, txTickets, ticket.CleaningAreaID, plannedStartAt, plannedEndAt, input.AllowOverlap, input.OverlapReason)
+			if err != nil {
+				return err
+			}
+			ticket.OverlapReason = overlapReason
+		}
+		return txTickets.CreateBatch(ctx, tickets)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tickets, nil
+}
+
+// UpdatePriority меняет приоритет тикета и пересчитывает целевой срок SLA
+func (s *TicketService) UpdatePriority(ctx context.Context, principal model.Principal, id string, rawPriority string, expectedVersion *int64) (*model.Ticket, error) {
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+
+	if strings.TrimSpace(rawPriority) == "" {
+		return nil, ErrInvalidInput
+	}
+
+	priority, err := parsePriority(rawPriority)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket, err := s.ticketRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if ticket.CreatedByOrgID != principal.OrgID {
+		return nil, ErrPermissionDenied
+	}
+
+	if err := checkVersion(expectedVersion, ticket.Version); err != nil {
+		return nil, err
+	}
+
+	// Приоритет закрытых и отмененных тикетов уже не имеет смысла
+	if ticket.Status == model.TicketStatusClosed || ticket.Status == model.TicketStatusCancelled {
+		return nil, ErrConflict
+	}
+
+	ticket.Priority = priority
+	ticket.SLADeadlineAt = s.slaDeadline(ticket)
+	if err := s.ticketRepo.Update(ctx, ticket); err != nil {
+		return nil, mapUpdateError(err)
+	}
+
+	return ticket, nil
+}
+
+// requestAcceptance переводит тикет в ожидание подтверждения подрядчиком
+func (s *TicketService) requestAcceptance(ticket *model.Ticket) {
+	dueAt := time.Now().Add(s.cfg.AcceptanceTimeout)
+	ticket.Acceptance = model.TicketAcceptancePending
+	ticket.AcceptDueAt = &dueAt
+	ticket.AcceptedAt = nil
+	ticket.EscalatedAt = nil
+}
+
+// Accept - подрядчик подтверждает, что принял тикет в работу
+func (s *TicketService) Accept(ctx context.Context, principal model.Principal, id string, expectedVersion *int64) (*model.Ticket, error) {
+	ticket, err := s.getPendingForContractor(ctx, principal, id, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ticket.Acceptance = model.TicketAcceptanceAccepted
+	ticket.AcceptedAt = &now
+	if err := s.ticketRepo.Update(ctx, ticket); err != nil {
+		return nil, mapUpdateError(err)
+	}
+
+	return ticket, nil
+}
+
+// Decline - подрядчик отказывается от тикета, тикет возвращается в КГУ на переназначение
+func (s *TicketService) Decline(ctx context.Context, principal model.Principal, id string, reason string, expectedVersion *int64) (*model.Ticket, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrInvalidInput
+	}
+
+	ticket, err := s.getPendingForContractor(ctx, principal, id, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ticket.Acceptance = model.TicketAcceptanceDeclined
+	ticket.DeclinedAt = &now
+	ticket.DeclineReason = &reason
+	if err := s.ticketRepo.Update(ctx, ticket); err != nil {
+		return nil, mapUpdateError(err)
+	}
+
+	return ticket, nil
+}
+
+type ReassignTicketInput struct {
+	ContractorID string
+	ContractID   string
+}
+
+// Reassign - КГУ передает отклоненный или неподтвержденный тикет другому подрядчику
+func (s *TicketService) Reassign(ctx context.Context, principal model.Principal, id string, input ReassignTicketInput, expectedVersion *int64) (*model.Ticket, error) {
+	if !principal.IsToo() {
+		return nil, ErrPermissionDenied
+	}
+
+	contractorID, err := uuid.Parse(input.ContractorID)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	contractID, err := uuid.Parse(input.ContractID)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	ticket, err := s.ticketRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if ticket.CreatedByOrgID != principal.OrgID {
+		return nil, ErrPermissionDenied
+	}
+
+	if err := checkVersion(expectedVersion, ticket.Version); err != nil {
+		return nil, err
+	}
+
+	// Принятый подрядчиком тикет переназначить нельзя
+	if ticket.Status != model.TicketStatusPlanned || ticket.Acceptance == model.TicketAcceptanceAccepted {
+		return nil, ErrConflict
+	}
+
+	ticket.ContractorID = contractorID
+	ticket.ContractID = &contractID
+	ticket.DeclinedAt = nil
+	ticket.DeclineReason = nil
+	s.requestAcceptance(ticket)
+	err = s.ticketRepo.Transaction(ctx, func(tickets *repository.TicketRepository, contracts *repository.ContractRepository) error {
+		if err := validateContract(ctx, contracts, principal, contractID, contractorID, ticket.PlannedStartAt, ticket.PlannedEndAt); err != nil {
+			return err
+		}
+		return tickets.Update(ctx, ticket)
+	})
+	if err != nil {
+		return nil, mapUpdateError(err)
+	}
+
+	return ticket, nil
+}
+
+// EscalateUnaccepted помечает тикеты, которые подрядчик не подтвердил в срок.
+// Вызывается планировщиком.
+func (s *TicketService) EscalateUnaccepted(ctx context.Context) (int, error) {
+	now := time.Now()
+	tickets, err := s.ticketRepo.ListAcceptanceOverdue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	escalated := 0
+	for i := range tickets {
+		ticket := &tickets[i]
+		ticket.EscalatedAt = &now
+		if err := s.ticketRepo.Update(ctx, ticket); err != nil {
+			// Тикет изменили параллельно - проверим его на следующем проходе
+			if errors.Is(err, repository.ErrVersionConflict) {
+				continue
+			}
+			return escalated, err
+		}
+		escalated++
+	}
+
+	return escalated, nil
+}
+
+func (s *TicketService) getPendingForContractor(ctx context.Context, principal model.Principal, id string, expectedVersion *int64) (*model.Ticket, error) {
+	if !principal.IsContractor() {
+		return nil, ErrPermissionDenied
+	}
+
+	ticket, err := s.ticketRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if ticket.ContractorID != principal.OrgID {
+		return nil, ErrPermissionDenied
+	}
+
+	if err := checkVersion(expectedVersion, ticket.Version); err != nil {
+		return nil, err
+	}
+
+	if ticket.Status != model.TicketStatusPlanned || ticket.Acceptance != model.TicketAcceptancePending {
+		return nil, ErrConflict
+	}
+
+	return ticket, nil
+}
+
+// slaDeadline рассчитывает целевой срок выполнения: начало работ + SLA приоритета,
+// но не позже планового окончания
+func (s *TicketService) slaDeadline(ticket *model.Ticket) *time.Time {
+	var sla time.Duration
+	switch ticket.Priority {
+	case model.TicketPriorityEmergency:
+		sla = s.cfg.EmergencySLA
+	case model.TicketPriorityHigh:
+		sla = s.cfg.HighSLA
+	default:
+		sla = s.cfg.NormalSLA
+	}
+
+	deadline := ticket.PlannedStartAt.Add(sla)
+	if deadline.After(ticket.PlannedEndAt) {
+		deadline = ticket.PlannedEndAt
+	}
+	return &deadline
+}
+
+func parsePriority(raw string) (model.TicketPriority, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return model.TicketPriorityNormal, nil
+	}
+	priority := model.TicketPriority(strings.ToUpper(raw))
+	if !priority.IsValid() {
+		return "", ErrInvalidInput
+	}
+	return priority, nil
 }
 
 func (s *TicketService) Get(ctx context.Context, principal model.Principal, id string) (*model.Ticket, error) {
@@ -117,7 +625,7 @@ func (s *TicketService) Get(ctx context.Context, principal model.Principal, id s
 		return nil, err
 	}
 
-	if !s.canAccessTicket(principal, ticket) {
+	if !canAccessTicket(principal, ticket) {
 		return nil, ErrPermissionDenied
 	}
 
@@ -141,10 +649,35 @@ func (s *TicketService) List(ctx context.Context, principal model.Principal, fil
 		filter.DriverID = &driverID
 	}
 
-	return s.ticketRepo.List(ctx, filter)
+	tickets, err := s.ticketRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Водители не участвуют в обсуждении тикетов, счетчик им не нужен
+	if principal.IsDriver() || len(tickets) == 0 {
+		return tickets, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(tickets))
+	for _, t := range tickets {
+		ids = append(ids, t.ID)
+	}
+
+	unread, err := s.commentRepo.CountUnread(ctx, principal.UserID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tickets {
+		count := unread[tickets[i].ID]
+		tickets[i].UnreadComments = &count
+	}
+
+	return tickets, nil
 }
 
-func (s *TicketService) Cancel(ctx context.Context, principal model.Principal, id string) error {
+func (s *TicketService) Cancel(ctx context.Context, principal model.Principal, id string, expectedVersion *int64) error {
 	// Только KGU ZKH может отменять тикеты
 	if !principal.IsToo() {
 		return ErrPermissionDenied
@@ -162,6 +695,10 @@ func (s *TicketService) Cancel(ctx context.Context, principal model.Principal, i
 		return ErrPermissionDenied
 	}
 
+	if err := checkVersion(expectedVersion, ticket.Version); err != nil {
+		return err
+	}
+
 	// Можно отменить только если нет фактов (нет рейсов и fact_start_at пустой)
 	if ticket.FactStartAt != nil {
 		return ErrConflict
@@ -178,10 +715,10 @@ func (s *TicketService) Cancel(ctx context.Context, principal model.Principal, i
 	}
 
 	ticket.Status = model.TicketStatusCancelled
-	return s.ticketRepo.Update(ctx, ticket)
+	return mapUpdateError(s.ticketRepo.Update(ctx, ticket))
 }
 
-func (s *TicketService) Close(ctx context.Context, principal model.Principal, id string) error {
+func (s *TicketService) Close(ctx context.Context, principal model.Principal, id string, expectedVersion *int64) error {
 	// KGU ZKH может закрывать тикеты после проверки
 	if !principal.IsToo() {
 		return ErrPermissionDenied
@@ -199,16 +736,20 @@ func (s *TicketService) Close(ctx context.Context, principal model.Principal, id
 		return ErrPermissionDenied
 	}
 
+	if err := checkVersion(expectedVersion, ticket.Version); err != nil {
+		return err
+	}
+
 	// Можно закрыть только если тикет в статусе COMPLETED
 	if ticket.Status != model.TicketStatusCompleted {
 		return ErrConflict
 	}
 
 	ticket.Status = model.TicketStatusClosed
-	return s.ticketRepo.Update(ctx, ticket)
+	return mapUpdateError(s.ticketRepo.Update(ctx, ticket))
 }
 
-func (s *TicketService) Complete(ctx context.Context, principal model.Principal, id string) error {
+func (s *TicketService) Complete(ctx context.Context, principal model.Principal, id string, expectedVersion *int64) error {
 	// Подрядчик может завершить тикет
 	if !principal.IsContractor() {
 		return ErrPermissionDenied
@@ -226,6 +767,10 @@ func (s *TicketService) Complete(ctx context.Context, principal model.Principal,
 		return ErrPermissionDenied
 	}
 
+	if err := checkVersion(expectedVersion, ticket.Version); err != nil {
+		return err
+	}
+
 	// Проверяем, что все рейсы закрыты (есть exit события и кузов пустой)
 	incompleteTrips, err := s.ticketRepo.CountIncompleteTripsByTicketID(ctx, ticket.ID)
 	if err != nil {
@@ -251,7 +796,7 @@ func (s *TicketService) Complete(ctx context.Context, principal model.Principal,
 	if ticket.FactEndAt == nil {
 		ticket.FactEndAt = &now
 	}
-	return s.ticketRepo.Update(ctx, ticket)
+	return mapUpdateError(s.ticketRepo.Update(ctx, ticket))
 }
 
 // TicketDetails содержит полную информацию о тикете
@@ -272,7 +817,7 @@ func (s *TicketService) GetDetails(ctx context.Context, principal model.Principa
 		return nil, err
 	}
 
-	if !s.canAccessTicket(principal, ticket) {
+	if !canAccessTicket(principal, ticket) {
 		return nil, ErrPermissionDenied
 	}
 
@@ -361,14 +906,14 @@ func (s *TicketService) OnTripCreated(ctx context.Context, ticketID uuid.UUID) e
 			now := time.Now()
 			ticket.Status = model.TicketStatusInProgress
 			ticket.FactStartAt = &now
-			return s.ticketRepo.Update(ctx, ticket)
+			return mapUpdateError(s.ticketRepo.Update(ctx, ticket))
 		}
 	}
 
 	return nil
 }
 
-func (s *TicketService) canAccessTicket(principal model.Principal, ticket *model.Ticket) bool {
+func canAccessTicket(principal model.Principal, ticket *model.Ticket) bool {
 	if principal.IsAkimat() {
 		return true
 	}
@@ -13,14 +13,16 @@ import (
 )
 
 type TripService struct {
-	tripRepo   *repository.TripRepository
-	ticketRepo *repository.TicketRepository
+	tripRepo       *repository.TripRepository
+	ticketRepo     *repository.TicketRepository
+	assignmentRepo *repository.AssignmentRepository
 }
 
-func NewTripService(tripRepo *repository.TripRepository, ticketRepo *repository.TicketRepository) *TripService {
+func NewTripService(tripRepo *repository.TripRepository, ticketRepo *repository.TicketRepository, assignmentRepo *repository.AssignmentRepository) *TripService {
 	return &TripService{
-		tripRepo:   tripRepo,
-		ticketRepo: ticketRepo,
+		tripRepo:       tripRepo,
+		ticketRepo:     ticketRepo,
+		assignmentRepo: assignmentRepo,
 	}
 }
 
@@ -149,6 +151,19 @@ func (s *TripService) Create(ctx context.Context, input CreateTripInput) (*model
 		exitAt = &parsed
 	}
 
+	// Рейс после замены относим к новой паре, только если камера зафиксировала ее технику.
+	// Зафиксированная техника рейса не меняется: рейс прежней техники остается за прежней парой
+	if ticketAssignmentID != nil && vehicleID != nil {
+		replacement, err := s.resolveReplacement(ctx, *ticketAssignmentID, entryAt)
+		if err != nil {
+			return nil, err
+		}
+		if replacement != nil && replacement.VehicleID == *vehicleID {
+			ticketAssignmentID = &replacement.ID
+			driverID = &replacement.DriverID
+		}
+	}
+
 	trip := &model.Trip{
 		TicketID:           ticketID,
 		TicketAssignmentID: ticketAssignmentID,
@@ -182,6 +197,32 @@ func (s *TripService) Create(ctx context.Context, input CreateTripInput) (*model
 	return trip, nil
 }
 
+// resolveReplacement находит назначение, действовавшее в момент at, если исходное
+// было заменено раньше. Возвращает nil, если замена не требуется
+func (s *TripService) resolveReplacement(ctx context.Context, assignmentID uuid.UUID, at time.Time) (*model.TicketAssignment, error) {
+	var resolved *model.TicketAssignment
+	current, err := s.assignmentRepo.GetByID(ctx, assignmentID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for !current.IsActive && current.UnassignedAt != nil && !at.Before(*current.UnassignedAt) {
+		next, err := s.assignmentRepo.GetReplacement(ctx, current.ID)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			break
+		}
+		resolved, current = next, next
+	}
+
+	return resolved, nil
+}
+
 func (s *TripService) ListByTicketID(ctx context.Context, principal model.Principal, ticketID string) ([]model.Trip, error) {
 	ticket, err := s.ticketRepo.GetByID(ctx, ticketID)
 	if err != nil {
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Job - периодическая фоновая задача
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Scheduler запускает задачи с общим интервалом до отмены контекста
+type Scheduler struct {
+	interval time.Duration
+	jobs     []Job
+	log      zerolog.Logger
+}
+
+func NewScheduler(interval time.Duration, log zerolog.Logger, jobs ...Job) *Scheduler {
+	return &Scheduler{
+		interval: interval,
+		jobs:     jobs,
+		log:      log,
+	}
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runAll(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) runAll(ctx context.Context) {
+	for _, job := range s.jobs {
+		if err := job.Run(ctx); err != nil {
+			s.log.Error().Err(err).Str("job", job.Name).Msg("scheduled job failed")
+		}
+	}
+}